@@ -0,0 +1,152 @@
+package goevolve
+
+import (
+	"sync"
+)
+
+// Adaptive is implemented by breeders that tune themselves from the report a
+// Population publishes after every generation.
+type Adaptive interface {
+	Adapt(report *PopulationReport)
+}
+
+func (multi MultiBreeder) Adapt(report *PopulationReport) {
+	for _, breeder := range multi {
+		adapt(breeder, report)
+	}
+}
+
+func adapt(x interface{}, report *PopulationReport) {
+	if adaptive, ok := x.(Adaptive); ok {
+		adaptive.Adapt(report)
+	}
+}
+
+type AdaptationRule int
+
+const (
+	// OneFifthRule raises the rate when more than a fifth of the last Window
+	// generations improved the best reward and lowers it when fewer did.
+	OneFifthRule AdaptationRule = iota
+	// StagnationRule raises the rate after Patience generations without
+	// improvement and lowers it again on every improvement.
+	StagnationRule
+	// DiversityRule raises the rate while the share of unique programs is below
+	// TargetDiversity and lowers it above.
+	DiversityRule
+)
+
+// RateAdapter scales a MutationBreeder's operator rates by a factor between
+// MinScale and MaxScale. Operators restricts the scaling to the named operators;
+// when empty every operator is scaled.
+type RateAdapter struct {
+	Rule               AdaptationRule
+	Window, Patience   int
+	TargetDiversity    float64
+	Factor             float64
+	MinScale, MaxScale float64
+	Operators          []string
+	scale, best        float64
+	started            bool
+	successes, seen    int
+	stale              int
+	lock               sync.Mutex
+}
+
+func newRateAdapter(rule AdaptationRule) *RateAdapter {
+	return &RateAdapter{Rule: rule, Window: 10, Patience: 20, TargetDiversity: 0.5, Factor: 1.22, MinScale: 0.1, MaxScale: 10, scale: 1}
+}
+
+func NewOneFifthRule(window int) *RateAdapter {
+	a := newRateAdapter(OneFifthRule)
+	a.Window = window
+	return a
+}
+
+func NewStagnationRule(patience int) *RateAdapter {
+	a := newRateAdapter(StagnationRule)
+	a.Patience = patience
+	return a
+}
+
+func NewDiversityRule(target float64) *RateAdapter {
+	a := newRateAdapter(DiversityRule)
+	a.TargetDiversity = target
+	return a
+}
+
+// Scale returns the current multiplier for the named operator.
+func (a *RateAdapter) Scale(name string) float64 {
+	if a == nil {
+		return 1
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if len(a.Operators) > 0 {
+		found := false
+		for _, op := range a.Operators {
+			found = found || op == name
+		}
+		if !found {
+			return 1
+		}
+	}
+	return a.scale
+}
+
+func (a *RateAdapter) Adapt(report *PopulationReport) {
+	if len(report.SolutionList) == 0 {
+		return
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	improved := !a.started || report.Best > a.best
+	if improved {
+		a.best = report.Best
+	}
+	a.started = true
+	switch a.Rule {
+	case OneFifthRule:
+		a.seen++
+		if improved {
+			a.successes++
+		}
+		if a.seen < Max(a.Window, 1) {
+			return
+		}
+		if ratio := float64(a.successes) / float64(a.seen); ratio > 0.2 {
+			a.grow()
+		} else if ratio < 0.2 {
+			a.shrink()
+		}
+		a.successes, a.seen = 0, 0
+	case StagnationRule:
+		if improved {
+			a.stale = 0
+			a.shrink()
+		} else if a.stale++; a.stale >= a.Patience {
+			a.stale = 0
+			a.grow()
+		}
+	case DiversityRule:
+		if diversity := float64(report.UniquePrograms) / float64(len(report.SolutionList)); diversity < a.TargetDiversity {
+			a.grow()
+		} else {
+			a.shrink()
+		}
+	}
+}
+
+func (a *RateAdapter) grow() {
+	a.scale *= a.Factor
+	if a.scale > a.MaxScale {
+		a.scale = a.MaxScale
+	}
+}
+
+func (a *RateAdapter) shrink() {
+	a.scale /= a.Factor
+	if a.scale < a.MinScale {
+		a.scale = a.MinScale
+	}
+}
@@ -0,0 +1,210 @@
+package goevolve
+
+import (
+	"github.com/tsavo/GoVirtual"
+)
+
+// ArgumentGenerator draws the operands of instructions. Generate returns a fresh
+// operand for op's index-th argument and Mutate perturbs an existing one.
+type ArgumentGenerator interface {
+	Generate(rng *SafeRNG, op *govirtual.Instruction, index int, labels []string) Argument
+	Mutate(rng *SafeRNG, op *govirtual.Instruction, index int, existing Argument, labels []string) Argument
+}
+
+// Constrained is implemented by breeders and generators that can be bounded by
+// the register count and heap size of the Population they breed for.
+type Constrained interface {
+	Constrain(registers, heapSize int)
+}
+
+func constrain(x interface{}, registers, heapSize int) {
+	if constrained, ok := x.(Constrained); ok {
+		constrained.Constrain(registers, heapSize)
+	}
+}
+
+// DefaultArguments is used by breeders without an ArgumentGenerator of their own
+// before a Population constrains them. It draws from 0-9999 as the original breeders did.
+var DefaultArguments ArgumentGenerator = &RangeArgumentGenerator{}
+
+// ArgumentSource is embedded by breeders that generate arguments. With no
+// Arguments set, Constrain gives the breeder a RangeArgumentGenerator.
+type ArgumentSource struct {
+	Arguments ArgumentGenerator
+}
+
+func (source *ArgumentSource) Constrain(registers, heapSize int) {
+	if source.Arguments == nil {
+		source.Arguments = NewRangeArgumentGenerator(registers, heapSize)
+		return
+	}
+	constrain(source.Arguments, registers, heapSize)
+}
+
+func (source ArgumentSource) arguments() ArgumentGenerator {
+	if source.Arguments == nil {
+		return DefaultArguments
+	}
+	return source.Arguments
+}
+
+func generateArguments(rng *SafeRNG, gen ArgumentGenerator, op *govirtual.Instruction, existing []Argument, labels []string) []Argument {
+	args := make([]Argument, len(op.Arguments))
+	for x := range op.Arguments {
+		if rng.Float64() < 0.5 && len(existing) == len(op.Arguments) && !existing[x].IsLabel() {
+			args[x] = gen.Mutate(rng, op, x, existing[x], labels)
+		} else {
+			args[x] = gen.Generate(rng, op, x, labels)
+		}
+	}
+	return args
+}
+
+// ArgumentRange bounds one operand of an instruction: values are drawn from
+// Constants when it is not empty, otherwise from Min to Max inclusive.
+type ArgumentRange struct {
+	Min, Max  int
+	Constants []int
+}
+
+// RangeArgumentGenerator keeps register references below Registers. Literals
+// are left unbounded, drawn from 0-9999 and free to drift when mutated, unless
+// Literals bounds them. They are drawn from the Constants pool with
+// ConstantChance. Instructions overrides the range of individual operands by
+// instruction name. HeapAddresses lists, by instruction name, the operands whose
+// literals are heap addresses; those are kept below HeapSize and never drawn
+// from Constants.
+type RangeArgumentGenerator struct {
+	Registers, HeapSize int
+	Literals            *ArgumentRange
+	Constants           []int
+	ConstantChance      float64
+	Instructions        map[string][]ArgumentRange
+	HeapAddresses       map[string][]int
+}
+
+func NewRangeArgumentGenerator(registers, heapSize int) *RangeArgumentGenerator {
+	return &RangeArgumentGenerator{Registers: registers, HeapSize: heapSize, ConstantChance: 0.5}
+}
+
+// Constrain fills in whichever bounds were left unset.
+func (gen *RangeArgumentGenerator) Constrain(registers, heapSize int) {
+	if gen.Registers == 0 {
+		gen.Registers = registers
+	}
+	if gen.HeapSize == 0 {
+		gen.HeapSize = heapSize
+	}
+}
+
+func (gen *RangeArgumentGenerator) operand(op *govirtual.Instruction, index int) (ArgumentRange, bool) {
+	if ranges, ok := gen.Instructions[op.Name]; ok && index < len(ranges) {
+		return ranges[index], true
+	}
+	return ArgumentRange{}, false
+}
+
+// heapAddress reports whether a literal operand is a heap address that HeapSize bounds.
+func (gen *RangeArgumentGenerator) heapAddress(op *govirtual.Instruction, index int, ref bool) bool {
+	if ref || gen.HeapSize <= 0 {
+		return false
+	}
+	for _, x := range gen.HeapAddresses[op.Name] {
+		if x == index {
+			return true
+		}
+	}
+	return false
+}
+
+// bounds returns the range of an operand and whether it is bounded at all.
+// Unbounded operands are drawn from 0-9999.
+func (gen *RangeArgumentGenerator) bounds(op *govirtual.Instruction, index int, ref bool) (int, int, bool) {
+	if r, ok := gen.operand(op, index); ok {
+		return r.Min, r.Max, true
+	}
+	if gen.heapAddress(op, index, ref) {
+		return 0, gen.HeapSize - 1, true
+	}
+	if ref && gen.Registers > 0 {
+		return 0, gen.Registers - 1, true
+	}
+	if !ref && gen.Literals != nil {
+		return gen.Literals.Min, gen.Literals.Max, true
+	}
+	return 0, 9999, false
+}
+
+func (gen *RangeArgumentGenerator) draw(rng *SafeRNG, op *govirtual.Instruction, index int, ref bool) int {
+	constants := gen.Constants
+	if r, ok := gen.operand(op, index); ok {
+		constants = r.Constants
+		if len(constants) > 0 {
+			return constants[rng.Int()%len(constants)]
+		}
+	} else if gen.heapAddress(op, index, ref) {
+		constants = nil
+	} else if !ref && gen.Literals != nil && len(gen.Literals.Constants) > 0 {
+		constants = gen.Literals.Constants
+	}
+	if !ref && len(constants) > 0 && rng.Float64() < gen.ConstantChance {
+		return constants[rng.Int()%len(constants)]
+	}
+	lo, hi, _ := gen.bounds(op, index, ref)
+	if hi < lo {
+		return lo
+	}
+	return lo + rng.Int()%(hi-lo+1)
+}
+
+func (gen *RangeArgumentGenerator) Generate(rng *SafeRNG, op *govirtual.Instruction, index int, labels []string) Argument {
+	arg := Argument{Type: op.Arguments[index].Type}
+	switch arg.Type {
+	case "ref":
+		arg.Ref = true
+		arg.Value = gen.draw(rng, op, index, true)
+	case "string":
+		if len(labels) > 0 {
+			arg.Label = labels[rng.Int()%len(labels)]
+		}
+	case "int":
+		arg.Ref = rng.Float64() < 0.5
+		arg.Value = gen.draw(rng, op, index, arg.Ref)
+	}
+	return arg
+}
+
+// Mutate keeps the operand or nudges it by 1, 10 or up to 1000 with even odds,
+// then clamps it back into range if it is bounded.
+func (gen *RangeArgumentGenerator) Mutate(rng *SafeRNG, op *govirtual.Instruction, index int, existing Argument, labels []string) Argument {
+	arg := existing
+	arg.Type = op.Arguments[index].Type
+	if rng.Float64() < 0.5 {
+		return arg
+	}
+	n := existing.Value
+	if rng.Float64() > 0.5 {
+		if rng.Float64() > 0.5 {
+			if rng.Float64() > 0.5 {
+				n += rng.Int() % 1000
+			} else {
+				n -= rng.Int() % 1000
+			}
+		} else if rng.Float64() > 0.5 {
+			n += 10
+		} else {
+			n -= 10
+		}
+	} else {
+		if rng.Float64() > 0.5 {
+			n++
+		} else {
+			n--
+		}
+	}
+	if lo, hi, ok := gen.bounds(op, index, arg.Ref); ok {
+		n = Max(lo, Min(hi, n))
+	}
+	arg.Value = n
+	return arg
+}
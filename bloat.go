@@ -0,0 +1,69 @@
+package goevolve
+
+import (
+	"github.com/tsavo/GoVirtual"
+)
+
+// limit truncates every program longer than MaxProgramLength lines and repairs
+// the labels the cut removed.
+func (s *Population) limit(rng *SafeRNG, programs []string) []string {
+	if s.MaxProgramLength <= 0 {
+		return programs
+	}
+	for x, program := range programs {
+		if ProgramLength(program) > s.MaxProgramLength {
+			programs[x] = truncate(rng, ParseGenome(program, nil), s.MaxProgramLength).String()
+		}
+	}
+	return programs
+}
+
+// truncate cuts genome to at most max lines and repairs its labels. Repair may
+// add a ":start" label, so the cut is made shorter until the repaired genome fits.
+func truncate(rng *SafeRNG, genome Genome, max int) Genome {
+	for n := Min(max, len(genome)); n > 0; n-- {
+		if out := genome[:n].Repair(rng); len(out) <= max {
+			return out
+		}
+	}
+	return Genome{}
+}
+
+// RunProgram runs program on p and scores it with e.
+func RunProgram(e Evaluator, p *govirtual.Processor, program string) Fitness {
+	if evaluator, ok := e.(ProgramEvaluator); ok {
+		return evaluator.EvaluateProgram(p, program)
+	}
+	p.Reset()
+	p.CompileAndLoad(program)
+	p.Run()
+	return e.Evaluate(p)
+}
+
+// SizePenaltyEvaluator subtracts Coefficient times the program's line count from
+// the wrapped Evaluator's reward.
+type SizePenaltyEvaluator struct {
+	Evaluator   Evaluator
+	Coefficient float64
+}
+
+func SizePenalty(e Evaluator, coefficient float64) *SizePenaltyEvaluator {
+	return &SizePenaltyEvaluator{e, coefficient}
+}
+
+func (penalty *SizePenaltyEvaluator) EvaluateProgram(p *govirtual.Processor, program string) Fitness {
+	f, _ := penalty.TryEvaluateProgram(p, program)
+	return f
+}
+
+// TryEvaluateProgram passes on the wrapped Evaluator's failures so they are not cached.
+func (penalty *SizePenaltyEvaluator) TryEvaluateProgram(p *govirtual.Processor, program string) (Fitness, error) {
+	f, err := TryRunProgram(penalty.Evaluator, p, program)
+	f.Reward -= penalty.Coefficient * float64(ProgramLength(program))
+	return f, err
+}
+
+// Evaluate cannot see the program, so it returns the wrapped score unpenalised.
+func (penalty *SizePenaltyEvaluator) Evaluate(p *govirtual.Processor) Fitness {
+	return penalty.Evaluator.Evaluate(p)
+}
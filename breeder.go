@@ -3,7 +3,6 @@ package goevolve
 import (
 	//"fmt"
 	"github.com/tsavo/GoVirtual"
-	"strconv"
 	"strings"
 )
 
@@ -18,6 +17,18 @@ func Breeders(breeders ...Breeder) *MultiBreeder {
 	return &out
 }
 
+func (multi MultiBreeder) InheritRNG(r *SafeRNG) {
+	for x, breeder := range multi {
+		inheritRNG(breeder, r.Derive(int64(x)))
+	}
+}
+
+func (multi MultiBreeder) Constrain(registers, heapSize int) {
+	for _, breeder := range multi {
+		constrain(breeder, registers, heapSize)
+	}
+}
+
 func (multi MultiBreeder) Breed(seeds []string) []string {
 	ret := make([]string, 0)
 	for _, x := range multi {
@@ -36,8 +47,8 @@ type CopyBreeder struct {
 	PopulationSize int
 }
 
-func NewCopyBreeder(size int) CopyBreeder {
-	return CopyBreeder{size}
+func NewCopyBreeder(size int) *CopyBreeder {
+	return &CopyBreeder{size}
 }
 
 func (cp CopyBreeder) Breed(initialPop []string) []string {
@@ -56,216 +67,70 @@ func (cp CopyBreeder) Breed(initialPop []string) []string {
 type RandomBreeder struct {
 	PopulationSize, ProgramLength int
 	*govirtual.InstructionSet
+	RNGSource
+	ArgumentSource
 }
 
 func NewRandomBreeder(popSize int, programLen int, is *govirtual.InstructionSet) *RandomBreeder {
-	return &RandomBreeder{popSize, programLen, is}
+	return &RandomBreeder{PopulationSize: popSize, ProgramLength: programLen, InstructionSet: is}
 }
 
 func (breeder RandomBreeder) Breed([]string) []string {
+	rng := breeder.random()
 	progs := make([]string, breeder.PopulationSize)
 	for x := 0; x < breeder.PopulationSize; x++ {
-		p := ":start\n"
-
+		genome := Genome{Gene{Label: ":start"}}
 		for y := 0; y < breeder.ProgramLength; y++ {
-			i := (*breeder.InstructionSet)[rng.Int()%len(*breeder.InstructionSet)]
-			for i.Infix || strings.HasPrefix(i.Name, ":") {
-				i = (*breeder.InstructionSet)[rng.Int()%len(*breeder.InstructionSet)]
-			}
-			p += i.Name + " " + ArgsForInstruction(i, nil, []string{":start"}) + "\n"
+			genome = append(genome, randomGene(rng, breeder.arguments(), breeder.InstructionSet, []string{":start"}))
 		}
-		progs[x] = p
+		progs[x] = genome.String()
 	}
 	return progs
 }
 
 type CrossoverBreeder struct {
 	PopulationSize int
+	Operator       CrossoverOperator
+	MaxLength      int
+	RNGSource
 }
 
-func NewCrossoverBreeder(popSize int) CrossoverBreeder {
-	return CrossoverBreeder{popSize}
+func NewCrossoverBreeder(popSize int) *CrossoverBreeder {
+	return &CrossoverBreeder{PopulationSize: popSize}
 }
 
+// Breed mates random pairs of seeds until PopulationSize children exist. Children
+// longer than MaxLength, when set, are truncated.
 func (breeder CrossoverBreeder) Breed(seeds []string) []string {
 	if len(seeds) == 0 {
 		return nil
 	}
-	outProg := make([]string, breeder.PopulationSize)
-	for i := 0; i < breeder.PopulationSize; i++ {
-		prog1 := strings.Split(seeds[rng.Int()%len(seeds)], "\n")
-		prog2 := strings.Split(seeds[rng.Int()%len(seeds)], "\n")
-
-		l1 := len(prog1)
-		l2 := len(prog2)
-		prog := make([]string, Max(l1, l2))
-		split := rng.Int() % Min(l1, l2)
-		endSplit := (rng.Int() % (Min(l1, l2) - split)) + split
-		for x := 0; x < len(prog); x++ {
-			if x > len(prog1)-1 || (x < endSplit && x >= split && x < len(prog2)) {
-				prog[x] = prog2[x]
+	rng := breeder.random()
+	outProg := make([]string, 0, breeder.PopulationSize)
+	for len(outProg) < breeder.PopulationSize {
+		prog1 := ParseGenome(seeds[rng.Int()%len(seeds)], nil)
+		prog2 := ParseGenome(seeds[rng.Int()%len(seeds)], nil)
+		for _, child := range breeder.Operator.Cross(rng, prog1, prog2) {
+			if len(outProg) == breeder.PopulationSize {
+				break
+			}
+			if breeder.MaxLength > 0 && len(child) > breeder.MaxLength {
+				child = truncate(rng, child, breeder.MaxLength)
 			} else {
-				prog[x] = prog1[x]
+				child = child.Repair(rng)
 			}
+			outProg = append(outProg, child.String())
 		}
-		outProg[i] = strings.Join(prog, "\n")
 	}
 	return outProg
 }
 
-type MutationBreeder struct {
-	PopulationSize int
-	MutationChance float64
-	*govirtual.InstructionSet
-}
-
-func NewMutationBreeder(popSize int, mutationChance float64, is *govirtual.InstructionSet) MutationBreeder {
-	return MutationBreeder{popSize, mutationChance, is}
-}
-
 func ArgsForInstruction(op *govirtual.Instruction, existing, labels []string) string {
-	args := make([]string, len(op.Arguments))
-	for x, arg := range op.Arguments {
-		if rng.Float64() < 0.5 && len(existing) == len(op.Arguments) && !strings.HasPrefix(existing[x], ":") {
-			if rng.Float64() < 0.5 {
-				args[x] = existing[x]
-				continue
-			}
-			num := existing[x]
-			if strings.HasPrefix(num, "#") {
-				num = num[1:]
-			}
-			n, _ := strconv.Atoi(num)
-			if rng.Float64() > 0.5 {
-				if rng.Float64() > 0.5 {
-					if rng.Float64() > 0.5 {
-						n += rng.Int() % 1000
-					} else {
-						n -= rng.Int() % 1000
-					}
-				} else if rng.Float64() > 0.5 {
-					n += 10
-				} else {
-					n -= 10
-				}
-			} else {
-				if rng.Float64() > 0.5 {
-					n++
-				} else {
-					n--
-				}
-			}
-			if strings.HasPrefix(existing[x], "#") {
-				args[x] = "#" + strconv.Itoa(n)
-			} else {
-				args[x] = strconv.Itoa(n)
-			}
-		} else {
-			switch arg.Type {
-			case "ref":
-				args[x] = "#" + strconv.Itoa(rng.SmallInt())
-			case "string":
-				args[x] = labels[rng.Int()%len(labels)]
-			case "int":
-				if rng.Float64() < 0.5 {
-					args[x] = "#" + strconv.Itoa(rng.SmallInt())
-				} else {
-					args[x] = strconv.Itoa(rng.SmallInt())
-				}
-			default:
-				args[x] = "0"
-			}
-		}
-	}
-	return strings.Join(args, ",")
-}
-
-func (breeder MutationBreeder) Breed(seeds []string) []string {
-	if len(seeds) == 0 {
-		return nil
+	args := make([]Argument, len(existing))
+	for x, arg := range existing {
+		args[x] = ParseArgument(arg)
 	}
-	out := make([]string, breeder.PopulationSize)
-	y := 0
-	for x := 0; x < breeder.PopulationSize; x++ {
-		y = y % len(seeds)
-		startProg := seeds[y]
-		labels := breeder.CompileProgram(startProg, nil).LabelNames()
-		prog := strings.Split(startProg, "\n")
-		outProg := ""
-		for _, op := range prog {
-			op = strings.TrimSpace(op)
-			if len(op) < 1 {
-				continue
-			}
-			if rng.Float64() < breeder.MutationChance {
-				if rng.Float64() < breeder.MutationChance {
-					for r := rng.Int() % 10; r < 10; r++ {
-						if rng.Float64() < 0.1 {
-							if rng.Float64() < 0.5 && len(labels) > 0 {
-								outProg += labels[rng.Int()%len(labels)] + "\n"
-							} else {
-								nl := ":" + USDict.RandomWord()
-								labels = append(labels, nl)
-								outProg += nl + "\n"
-							}
-						} else {
-							i := (*breeder.InstructionSet)[rng.Int()%len(*breeder.InstructionSet)]
-							for i.Infix || strings.HasPrefix(i.Name, ":") {
-								i = (*breeder.InstructionSet)[rng.Int()%len(*breeder.InstructionSet)]
-							}
-							args := ArgsForInstruction(i, nil, labels)
-							outProg += i.Name + " " + args + "\n"
-						}
-					}
-				}
-				if rng.Float64() < 0.1 && len(outProg) > 0 {
-					continue
-				}
-				if rng.Float64() < 0.1 {
-					if rng.Float64() < 0.5 && len(labels) > 0 {
-						outProg += labels[rng.Int()%len(labels)] + "\n"
-					} else {
-						nl := ":" + USDict.RandomWord()
-						labels = append(labels, nl)
-						outProg += nl + "\n"
-					}
-					continue
-				}
-				i := (*breeder.InstructionSet)[rng.Int()%len(*breeder.InstructionSet)]
-				for i.Infix || strings.HasPrefix(i.Name, ":") {
-					i = (*breeder.InstructionSet)[rng.Int()%len(*breeder.InstructionSet)]
-				}
-				parts := strings.Split(op, " ")
-				if rng.Float64() > 0.5 && strings.HasPrefix(parts[0], ":") {
-					if rng.Float64() > 0.5 && len(labels) > 0 {
-						outProg += labels[rng.Int()%len(labels)] + "\n"
-						continue
-					} else if rng.Float64() > 0.5 {
-						nl := ":" + USDict.RandomWord()
-						labels = append(labels, nl)
-						outProg += nl + "\n"
-						continue
-					}
-				} else if rng.Float64() > 0.5 && !strings.HasPrefix(parts[0], ":") {
-					i = (*breeder.InstructionSet).Compile(parts[0]).Instruction
-				}
-				if strings.HasPrefix(parts[0], ":") {
-					outProg += parts[0]
-				} else {
-					if len(parts) > 1 {
-						outProg += parts[0] + " " + ArgsForInstruction(i, strings.Split(parts[1], ","), labels) + "\n"
-					} else {
-						outProg += parts[0] + " " + ArgsForInstruction(i, nil, labels) + "\n"
-					}
-				}
-			} else {
-				outProg += op + "\n"
-			}
-		}
-		y++
-		out = append(out, outProg)
-	}
-	return out
+	return joinArguments(generateArguments(rng, DefaultArguments, op, args, labels))
 }
 
 type InfluxBreeder chan []string
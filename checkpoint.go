@@ -0,0 +1,157 @@
+package goevolve
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"time"
+)
+
+// On disk a checkpoint is CheckpointMagic, the format version as a big-endian
+// uint32 and the gob encoded Checkpoint.
+const (
+	CheckpointMagic   = "GOEVOLVE"
+	CheckpointVersion = 2
+)
+
+type PopulationCheckpoint struct {
+	Id, Generation int
+	Seed           int64
+	Programs       []string
+}
+
+type Checkpoint struct {
+	Version   int
+	Time      time.Time
+	Seed      int64
+	Islands   []PopulationCheckpoint
+	Champions Champions
+}
+
+// Checkpoint captures the programs of the Population's latest surviving
+// generation, the generation's number and the Population's seed. A Population
+// restored from it breeds the same generations the original would have.
+func (s *Population) Checkpoint() PopulationCheckpoint {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	programs := make([]string, len(s.programs))
+	copy(programs, s.programs)
+	return PopulationCheckpoint{s.Id, s.Generation, s.random().CurrentSeed(), programs}
+}
+
+// Restore makes the next Run resume from cp instead of breeding a fresh population.
+func (s *Population) Restore(cp PopulationCheckpoint) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.done != nil {
+		return errors.New("goevolve: cannot restore a running population")
+	}
+	s.Generation = cp.Generation
+	s.RNG = NewRNG(cp.Seed)
+	s.programs = make([]string, len(cp.Programs))
+	copy(s.programs, cp.Programs)
+	return nil
+}
+
+// checkpointStream is the stream a restored evolver continues on, derived from
+// the one it was checkpointed with.
+const checkpointStream = -1
+
+// Checkpoint records the state of every local island without disturbing the
+// running experiment: the evolver's seed is derived, not drawn, from its RNG.
+func (self *IslandEvolver) Checkpoint() *Checkpoint {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	cp := &Checkpoint{Version: CheckpointVersion, Time: time.Now(), Seed: self.random().Derive(checkpointStream).CurrentSeed()}
+	for _, island := range self.islands {
+		if island.Population != nil {
+			cp.Islands = append(cp.Islands, island.Population.Checkpoint())
+		}
+	}
+	cp.Champions = make(Champions, len(self.Champions))
+	copy(cp.Champions, self.Champions)
+	return cp
+}
+
+// Restore resumes every island from cp. Islands must be added with the same ids
+// they had when the checkpoint was taken, and Restore must be called before Start.
+func (self *IslandEvolver) Restore(cp *Checkpoint) error {
+	if cp.Version != CheckpointVersion {
+		return fmt.Errorf("goevolve: unsupported checkpoint version %d", cp.Version)
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if self.ctx != nil {
+		return errors.New("goevolve: cannot restore a running evolver")
+	}
+	for _, island := range cp.Islands {
+		found := false
+		for _, added := range self.islands {
+			if added.Id == island.Id && added.Population != nil {
+				if err := added.Population.Restore(island); err != nil {
+					return err
+				}
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("goevolve: checkpoint island #%d has no matching population", island.Id)
+		}
+	}
+	self.Champions = make(Champions, len(cp.Champions))
+	copy(self.Champions, cp.Champions)
+	self.RNG = NewRNG(cp.Seed)
+	return nil
+}
+
+func (self *IslandEvolver) WriteCheckpoint(path string) error {
+	b := new(bytes.Buffer)
+	if err := EncodeCheckpoint(b, self.Checkpoint()); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, b.Bytes())
+}
+
+func EncodeCheckpoint(w io.Writer, cp *Checkpoint) error {
+	if _, err := io.WriteString(w, CheckpointMagic); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.BigEndian, uint32(cp.Version)); err != nil {
+		return err
+	}
+	return gob.NewEncoder(w).Encode(cp)
+}
+
+func DecodeCheckpoint(r io.Reader) (*Checkpoint, error) {
+	magic := make([]byte, len(CheckpointMagic))
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return nil, err
+	}
+	if string(magic) != CheckpointMagic {
+		return nil, errors.New("goevolve: not a checkpoint")
+	}
+	var version uint32
+	if err := binary.Read(r, binary.BigEndian, &version); err != nil {
+		return nil, err
+	}
+	if version != CheckpointVersion {
+		return nil, fmt.Errorf("goevolve: unsupported checkpoint version %d", version)
+	}
+	cp := new(Checkpoint)
+	if err := gob.NewDecoder(r).Decode(cp); err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+func ReadCheckpoint(path string) (*Checkpoint, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeCheckpoint(bytes.NewReader(data))
+}
@@ -0,0 +1,183 @@
+package goevolve
+
+import (
+	"github.com/tsavo/GoVirtual"
+)
+
+type CrossoverOperator int
+
+const (
+	// SegmentCrossover copies one parent and overwrites a line range with the
+	// other parent's lines at the same positions.
+	SegmentCrossover CrossoverOperator = iota
+	// OnePointCrossover swaps the tails of both parents after a common cut.
+	OnePointCrossover
+	// TwoPointCrossover swaps a middle section whose bounds are chosen
+	// independently in each parent, so children can change length.
+	TwoPointCrossover
+	// UniformCrossover picks every line from either parent with even odds.
+	UniformCrossover
+)
+
+func NewOnePointCrossover(children, maxLength int) *CrossoverBreeder {
+	return &CrossoverBreeder{PopulationSize: children, Operator: OnePointCrossover, MaxLength: maxLength}
+}
+
+func NewTwoPointCrossover(children, maxLength int) *CrossoverBreeder {
+	return &CrossoverBreeder{PopulationSize: children, Operator: TwoPointCrossover, MaxLength: maxLength}
+}
+
+func NewUniformCrossover(children, maxLength int) *CrossoverBreeder {
+	return &CrossoverBreeder{PopulationSize: children, Operator: UniformCrossover, MaxLength: maxLength}
+}
+
+// Cross mates two parents. SegmentCrossover yields one child, the others two complementary ones.
+func (op CrossoverOperator) Cross(rng *SafeRNG, prog1, prog2 Genome) []Genome {
+	switch op {
+	case OnePointCrossover:
+		return onePointCrossover(rng, prog1, prog2)
+	case TwoPointCrossover:
+		return twoPointCrossover(rng, prog1, prog2)
+	case UniformCrossover:
+		return uniformCrossover(rng, prog1, prog2)
+	default:
+		return segmentCrossover(rng, prog1, prog2)
+	}
+}
+
+func segmentCrossover(rng *SafeRNG, prog1, prog2 Genome) []Genome {
+	l1 := len(prog1)
+	l2 := len(prog2)
+	if Min(l1, l2) == 0 {
+		return []Genome{append(prog1.Clone(), prog2.Clone()...)}
+	}
+	prog := make(Genome, Max(l1, l2))
+	split := rng.Int() % Min(l1, l2)
+	endSplit := (rng.Int() % (Min(l1, l2) - split)) + split
+	for x := 0; x < len(prog); x++ {
+		if x > len(prog1)-1 || (x < endSplit && x >= split && x < len(prog2)) {
+			prog[x] = prog2[x].Clone()
+		} else {
+			prog[x] = prog1[x].Clone()
+		}
+	}
+	return []Genome{prog}
+}
+
+func splice(parts ...Genome) Genome {
+	out := Genome{}
+	for _, part := range parts {
+		out = append(out, part.Clone()...)
+	}
+	return out
+}
+
+func onePointCrossover(rng *SafeRNG, prog1, prog2 Genome) []Genome {
+	cut := rng.Int() % (Min(len(prog1), len(prog2)) + 1)
+	return []Genome{splice(prog1[:cut], prog2[cut:]), splice(prog2[:cut], prog1[cut:])}
+}
+
+// cutPoints returns a random a <= b within a genome of length n.
+func cutPoints(rng *SafeRNG, n int) (int, int) {
+	a, b := rng.Int()%(n+1), rng.Int()%(n+1)
+	if a > b {
+		a, b = b, a
+	}
+	return a, b
+}
+
+func twoPointCrossover(rng *SafeRNG, prog1, prog2 Genome) []Genome {
+	a1, b1 := cutPoints(rng, len(prog1))
+	a2, b2 := cutPoints(rng, len(prog2))
+	return []Genome{splice(prog1[:a1], prog2[a2:b2], prog1[b1:]), splice(prog2[:a2], prog1[a1:b1], prog2[b2:])}
+}
+
+func uniformCrossover(rng *SafeRNG, prog1, prog2 Genome) []Genome {
+	child1, child2 := Genome{}, Genome{}
+	for x := 0; x < Max(len(prog1), len(prog2)); x++ {
+		switch {
+		case x >= len(prog1):
+			child1 = append(child1, prog2[x].Clone())
+		case x >= len(prog2):
+			child1 = append(child1, prog1[x].Clone())
+		case rng.Float64() < 0.5:
+			child1 = append(child1, prog1[x].Clone())
+			child2 = append(child2, prog2[x].Clone())
+		default:
+			child1 = append(child1, prog2[x].Clone())
+			child2 = append(child2, prog1[x].Clone())
+		}
+	}
+	return []Genome{child1, child2}
+}
+
+// DefaultTerminators are the instruction name prefixes that end a basic block.
+var DefaultTerminators = []string{"jump", "call", "return"}
+
+// BlockCrossoverBreeder exchanges whole basic blocks between two parents. Blocks
+// start at the labels the compiled program defines and end after any instruction
+// whose name starts with one of Terminators. Imported labels that clash with the
+// child's are renamed, and any jump target left undefined is remapped to a label
+// the child defines.
+type BlockCrossoverBreeder struct {
+	PopulationSize int
+	Terminators    []string
+	*govirtual.InstructionSet
+	RNGSource
+}
+
+func NewBlockCrossoverBreeder(popSize int, is *govirtual.InstructionSet) *BlockCrossoverBreeder {
+	return &BlockCrossoverBreeder{PopulationSize: popSize, Terminators: DefaultTerminators, InstructionSet: is}
+}
+
+// blocks splits program into basic blocks, taking its labels from the compiled program.
+func (breeder BlockCrossoverBreeder) blocks(program string) []Genome {
+	genome := ParseGenome(program, breeder.InstructionSet)
+	labels := genome.Labels()
+	if breeder.InstructionSet != nil {
+		labels = breeder.InstructionSet.CompileProgram(program, nil).LabelNames()
+	}
+	return genome.BasicBlocks(labels, breeder.Terminators)
+}
+
+func (breeder BlockCrossoverBreeder) Breed(seeds []string) []string {
+	if len(seeds) == 0 {
+		return nil
+	}
+	rng := breeder.random()
+	out := make([]string, breeder.PopulationSize)
+	for i := 0; i < breeder.PopulationSize; i++ {
+		mother := breeder.blocks(seeds[rng.Int()%len(seeds)])
+		father := breeder.blocks(seeds[rng.Int()%len(seeds)])
+		for swaps := rng.Int()%len(father) + 1; swaps > 0; swaps-- {
+			x := rng.Int() % len(mother)
+			mother[x] = breeder.adopt(rng, mother, x, father[rng.Int()%len(father)].Clone())
+		}
+		child := Genome{}
+		for _, block := range mother {
+			child = append(child, block...)
+		}
+		out[i] = child.Repair(rng).String()
+	}
+	return out
+}
+
+// adopt prepares block to replace blocks[at], renaming its label if another block already defines it.
+func (breeder BlockCrossoverBreeder) adopt(rng *SafeRNG, blocks []Genome, at int, block Genome) Genome {
+	if len(block) == 0 || !block[0].IsLabel() {
+		return block
+	}
+	labels := make([]string, 0)
+	for x, other := range blocks {
+		if x != at {
+			labels = append(labels, other.Labels()...)
+		}
+	}
+	for _, label := range labels {
+		if label == block[0].Label {
+			block.renameLabel(label, newLabel(rng, append(labels, block.Labels()...)))
+			break
+		}
+	}
+	return block
+}
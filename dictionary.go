@@ -28,7 +28,11 @@ func NewDictionary(name string) *Dictionary {
 }
 
 func (dict *Dictionary) RandomWord() string {
-	return (*dict)[rng.Int()%len(*dict)]
+	return dict.RandomWordFrom(rng)
+}
+
+func (dict *Dictionary) RandomWordFrom(r *SafeRNG) string {
+	return (*dict)[r.Int()%len(*dict)]
 }
 
 var USDict = NewDictionary("US.dic")
@@ -0,0 +1,109 @@
+// +build ignore
+
+// Runs a symbolic regression across processes. Start an evolver with
+//
+//	go run distributed.go -listen :4000
+//
+// then join it from as many workers as you like, on this or other machines:
+//
+//	go run distributed.go -join localhost:4000 -islands 2
+//
+// Workers can in turn hand their evaluations to evaluation servers:
+//
+//	go run distributed.go -evaluate :4100
+//	go run distributed.go -evaluate :4101
+//	go run distributed.go -join localhost:4000 -evaluators http://localhost:4100,http://localhost:4101
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/tsavo/GoEvolve"
+	"github.com/tsavo/GoVirtual"
+	"log"
+	"strings"
+)
+
+func DefineInstructions() *govirtual.InstructionSet {
+	i := govirtual.NewInstructionSet()
+	i.Operator("set", func(p *govirtual.Processor, m *govirtual.Memory) {
+		p.Registers.Set((*m).Get(0), (*m).Get(1))
+	})
+	i.Operator("copy", func(p *govirtual.Processor, m *govirtual.Memory) {
+		p.Registers.Set((*m).Get(0), p.Registers.Get((*m).Get(1)))
+	})
+	i.Operator("add", func(p *govirtual.Processor, m *govirtual.Memory) {
+		p.Registers.Set((*m).Get(0), p.Registers.Get((*m).Get(0))+p.Registers.Get((*m).Get(1)))
+	})
+	i.Operator("subtract", func(p *govirtual.Processor, m *govirtual.Memory) {
+		p.Registers.Set((*m).Get(0), p.Registers.Get((*m).Get(0))-p.Registers.Get((*m).Get(1)))
+	})
+	i.Operator("multiply", func(p *govirtual.Processor, m *govirtual.Memory) {
+		p.Registers.Set((*m).Get(0), p.Registers.Get((*m).Get(0))*p.Registers.Get((*m).Get(1)))
+	})
+	return i
+}
+
+// Cases for f(x) = x*x + 3x - 2, with x in register 0 and f(x) expected in register 1.
+func Cases() []goevolve.TestCase {
+	cases := make([]goevolve.TestCase, 0)
+	for x := -10; x <= 10; x++ {
+		cases = append(cases, goevolve.TestCase{Inputs: []int{x}, Expected: []int{x*x + 3*x - 2}})
+	}
+	return cases
+}
+
+func NewEvaluator() *goevolve.CaseEvaluator {
+	eval := goevolve.NewCaseEvaluator(Cases(), []int{0}, []int{1}, goevolve.AbsoluteError)
+	eval.Registers = true
+	return eval
+}
+
+func NewIsland(is *govirtual.InstructionSet, eval goevolve.Evaluator) *goevolve.Population {
+	heap := make(govirtual.Memory, 4)
+	term := *govirtual.NewCostTerminationCondition(100000)
+	breeder := goevolve.Breeders(goevolve.NewCopyBreeder(10), goevolve.NewRandomBreeder(20, 8, is), goevolve.NewMutationBreeder(40, 0.1, is), goevolve.NewCrossoverBreeder(30))
+	selector := goevolve.AndSelect(goevolve.TopX(10), goevolve.Tournament(20))
+	return goevolve.NewPopulation(0, &heap, 4, is, term, breeder, eval, selector)
+}
+
+func main() {
+	listen := flag.String("listen", "", "address to serve the evolver on")
+	join := flag.String("join", "", "address of the evolver to join")
+	islands := flag.Int("islands", 1, "number of islands to run when joining")
+	evaluate := flag.String("evaluate", "", "address to serve evaluations on")
+	evaluators := flag.String("evaluators", "", "comma separated evaluation server URLs to use when joining")
+	flag.Parse()
+	switch {
+	case *listen != "":
+		evolver := goevolve.NewIslandEvolver()
+		evolver.AddSink(goevolve.SinkFunc(func(champion goevolve.Champion) error {
+			fmt.Printf("Champion from island #%d, generation %d, reward %v:\n%s\n", champion.Island, champion.Generation, champion.Reward, champion.Programs[0])
+			return nil
+		}))
+		evolver.Start(context.Background())
+		log.Fatal(evolver.ListenAndServe(*listen))
+	case *join != "":
+		is := DefineInstructions()
+		populations := make([]*goevolve.Population, *islands)
+		for x := range populations {
+			if *evaluators == "" {
+				populations[x] = NewIsland(is, NewEvaluator())
+				continue
+			}
+			urls := strings.Split(*evaluators, ",")
+			populations[x] = NewIsland(is, goevolve.NewRemoteEvaluator(urls...))
+			populations[x].Workers = 4 * len(urls)
+		}
+		if err := goevolve.NewWorker(*join, populations...).Run(context.Background()); err != nil {
+			log.Fatal(err)
+		}
+	case *evaluate != "":
+		heap := make(govirtual.Memory, 4)
+		term := *govirtual.NewCostTerminationCondition(100000)
+		log.Fatal(goevolve.NewEvaluationServer(4, &heap, 4, DefineInstructions(), term, NewEvaluator()).ListenAndServe(*evaluate))
+	default:
+		flag.Usage()
+	}
+}
@@ -4,10 +4,46 @@ import (
 	"github.com/tsavo/GoVirtual"
 )
 
+// Fitness scores a program; higher is better. Objectives optionally carries one
+// score per objective for multi-objective selection, and Behavior optionally
+// describes what the program did for novelty search.
+type Fitness struct {
+	Reward     float64
+	Objectives []float64
+	Behavior   []float64
+}
+
+func Score(reward float64, objectives ...float64) Fitness {
+	return Fitness{Reward: reward, Objectives: objectives}
+}
+
 type Evaluator interface {
+	Evaluate(*govirtual.Processor) Fitness
+}
+
+// IntEvaluator is the original integer Evaluator; wrap it with FromInt.
+type IntEvaluator interface {
 	Evaluate(*govirtual.Processor) int
 }
 
+type intEvaluator struct {
+	IntEvaluator
+}
+
+func FromInt(e IntEvaluator) Evaluator {
+	return intEvaluator{e}
+}
+
+func (e intEvaluator) Evaluate(p *govirtual.Processor) Fitness {
+	return Fitness{Reward: float64(e.IntEvaluator.Evaluate(p))}
+}
+
+type EvaluatorFunc func(*govirtual.Processor) Fitness
+
+func (f EvaluatorFunc) Evaluate(p *govirtual.Processor) Fitness {
+	return f(p)
+}
+
 type MultiEvaluator []*Evaluator
 
 func NewMultiEvaluator(e ...*Evaluator) *MultiEvaluator {
@@ -18,10 +54,15 @@ func NewMultiEvaluator(e ...*Evaluator) *MultiEvaluator {
 	return m
 }
 
-func (multi *MultiEvaluator) Evaluate(p *govirtual.Processor) int {
-	e := int(0)
-	for _, x := range *multi {
-		e += (*x).Evaluate(p)
+// Evaluate sums the rewards of every evaluator and keeps each one as an objective.
+// Their behaviors are concatenated.
+func (multi *MultiEvaluator) Evaluate(p *govirtual.Processor) Fitness {
+	e := Fitness{Objectives: make([]float64, len(*multi))}
+	for i, x := range *multi {
+		f := (*x).Evaluate(p)
+		e.Reward += f.Reward
+		e.Objectives[i] = f.Reward
+		e.Behavior = append(e.Behavior, f.Behavior...)
 	}
 	return e
 }
@@ -39,8 +80,13 @@ func Inverse(e Evaluator) *InverseEvaluator {
 	return &InverseEvaluator{&e}
 }
 
-func (inverse InverseEvaluator) Evaluate(p *govirtual.Processor) int {
-	return (*inverse.Evaluator).Evaluate(p) * -1
+func (inverse InverseEvaluator) Evaluate(p *govirtual.Processor) Fitness {
+	f := (*inverse.Evaluator).Evaluate(p)
+	objectives := make([]float64, len(f.Objectives))
+	for i, o := range f.Objectives {
+		objectives[i] = o * -1
+	}
+	return Fitness{f.Reward * -1, objectives, f.Behavior}
 }
 
 type TimeEvaluator struct{}
@@ -49,8 +95,8 @@ func NewTimeEvaluator() *TimeEvaluator {
 	return &TimeEvaluator{}
 }
 
-func (t *TimeEvaluator) Evaluate(p *govirtual.Processor) int {
-	return Now() - p.StartTime
+func (t *TimeEvaluator) Evaluate(p *govirtual.Processor) Fitness {
+	return Fitness{Reward: float64(Now() - p.StartTime)}
 }
 
 type CostEvaluator struct{}
@@ -59,6 +105,6 @@ func NewCostEvaluator() *CostEvaluator {
 	return &CostEvaluator{}
 }
 
-func (c *CostEvaluator) Evaluate(p *govirtual.Processor) int {
-	return p.Cost()
+func (c *CostEvaluator) Evaluate(p *govirtual.Processor) Fitness {
+	return Fitness{Reward: float64(p.Cost())}
 }
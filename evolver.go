@@ -1,26 +1,38 @@
 package goevolve
 
 import (
-	"bufio"
+	"context"
+	"fmt"
 	"github.com/tsavo/GoVirtual"
 	"log"
-	"os"
-	"os/exec"
-	"runtime"
 	"sort"
-	"time"
+	"sync"
 )
 
 type IslandEvolver struct {
-	ChampionSize         int
 	PopulationReportChan chan *PopulationReport
-	InfluxBreeder
-	lastId int
+	RNGSource
+	Migration Migration
+	Store     SolutionStore
+	Champions Champions
+	Sinks     MultiSink
+	lastId    int
+	islands   []*Island
+	inboxes   map[int]InfluxBreeder
+	reports   map[int]*PopulationReport
+	ctx       context.Context
+	cancel    context.CancelFunc
+	wait      sync.WaitGroup
+	lock      sync.Mutex
 }
 
+// Champion is a program that beat every earlier one, with the island and
+// generation it was found in.
 type Champion struct {
-	Reward   int
-	Programs []string
+	Fitness
+	Programs   []string
+	Island     int
+	Generation int
 }
 
 type Champions []Champion
@@ -32,59 +44,229 @@ func (s Champions) Less(i, j int) bool {
 }
 
 func NewIslandEvolver() *IslandEvolver {
-	i := IslandEvolver{0, make(chan *PopulationReport, 100), make(InfluxBreeder, 100), 0}
-	go i.Interbreed()
-	return &i
+	return &IslandEvolver{PopulationReportChan: make(chan *PopulationReport, 100), Migration: DefaultMigration(), Store: NewMemoryStore(DefaultStoreShards), inboxes: make(map[int]InfluxBreeder), reports: make(map[int]*PopulationReport)}
 }
 
-func (self *IslandEvolver) AddPopulation(heap *govirtual.Memory, registerSize int, is *govirtual.InstructionSet, term govirtual.TerminationCondition, breeder Breeder, eval Evaluator, selector Selector) {
-	breeders := Breeders(breeder, self.InfluxBreeder)
-	population := NewPopulation(self.lastId, heap, registerSize, is, term, breeders, eval, selector)
+func (self *IslandEvolver) AddPopulation(heap *govirtual.Memory, registerSize int, is *govirtual.InstructionSet, term govirtual.TerminationCondition, breeder Breeder, eval Evaluator, selector Selector) *Island {
+	return self.AddIsland(IslandConfig{Heap: heap, RegisterLength: registerSize, InstructionSet: is, TerminationCondition: term, Breeder: breeder, Evaluator: eval, Selector: selector})
+}
+
+// AddIsland creates an island from config and returns its handle.
+func (self *IslandEvolver) AddIsland(config IslandConfig) *Island {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	inbox := make(InfluxBreeder, 100)
+	self.inboxes[self.lastId] = inbox
+	breeders := Breeders(config.Breeder, inbox)
+	population := NewPopulation(self.lastId, config.Heap, config.RegisterLength, config.InstructionSet, config.TerminationCondition, breeders, config.Evaluator, config.Selector)
 	population.PopulationReportChan = self.PopulationReportChan
-	go population.Run()
+	population.Store = config.Store
+	if population.Store == nil {
+		population.Store = Namespaced(self.Store, fmt.Sprintf("island-%d/", self.lastId))
+	}
+	island := &Island{Id: self.lastId, Config: config, Population: population, evolver: self}
+	self.islands = append(self.islands, island)
+	if self.ctx != nil {
+		self.startPopulation(population)
+	}
 	self.lastId++
-	self.ChampionSize++
+	return island
+}
+
+// Island returns the island with the given id, or nil if there is none.
+func (self *IslandEvolver) Island(id int) *Island {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	for _, island := range self.islands {
+		if island.Id == id {
+			return island
+		}
+	}
+	return nil
+}
+
+func (self *IslandEvolver) Islands() []*Island {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return append([]*Island{}, self.islands...)
+}
+
+// RemoveIsland stops the island with the given id and stops migrating to it.
+// It returns false if there is no such island.
+func (self *IslandEvolver) RemoveIsland(id int) bool {
+	self.lock.Lock()
+	var removed *Island
+	for x, island := range self.islands {
+		if island.Id == id {
+			removed = island
+			self.islands = append(self.islands[:x:x], self.islands[x+1:]...)
+			break
+		}
+	}
+	delete(self.inboxes, id)
+	delete(self.reports, id)
+	self.lock.Unlock()
+	if removed == nil {
+		return false
+	}
+	removed.halt(false)
+	return true
+}
+
+func (self *IslandEvolver) AddSink(sink ChampionSink) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.Sinks = append(self.Sinks, sink)
+}
+
+// Start runs every island and the Interbreed loop until ctx is done or Stop is called.
+// Islands added after Start are started immediately.
+func (self *IslandEvolver) Start(ctx context.Context) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if self.ctx != nil {
+		return
+	}
+	self.ctx, self.cancel = context.WithCancel(ctx)
+	for _, island := range self.islands {
+		if island.Population != nil {
+			self.startPopulation(island.Population)
+		}
+	}
+	self.wait.Add(1)
+	go func() {
+		defer self.wait.Done()
+		self.Interbreed(self.ctx)
+	}()
+}
+
+// startPopulation gives the island its own stream of the evolver's RNG and runs it.
+func (self *IslandEvolver) startPopulation(population *Population) {
+	population.InheritRNG(self.random().Derive(int64(population.Id)))
+	population.Start(self.ctx)
+}
+
+// Stop cancels the generation in progress on every island, then stops the Interbreed loop.
+func (self *IslandEvolver) Stop() {
+	self.shutdown(false)
+}
+
+// Drain lets every island finish its current generation, then stops the Interbreed loop.
+func (self *IslandEvolver) Drain() {
+	self.shutdown(true)
 }
 
-func (self *IslandEvolver) Interbreed() {
+func (self *IslandEvolver) shutdown(drain bool) {
+	self.lock.Lock()
+	islands := self.islands
+	cancel := self.cancel
+	self.lock.Unlock()
+	var wait sync.WaitGroup
+	for _, island := range islands {
+		wait.Add(1)
+		go func(island *Island) {
+			defer wait.Done()
+			island.halt(drain)
+		}(island)
+	}
+	wait.Wait()
+	if cancel != nil {
+		cancel()
+	}
+	self.wait.Wait()
+}
+
+func (self *IslandEvolver) Wait() {
+	self.lock.Lock()
+	islands := self.islands
+	self.lock.Unlock()
+	for _, island := range islands {
+		island.wait()
+	}
+	self.wait.Wait()
+}
+
+// Interbreed collects every island's reports, keeps track of the champion and
+// sends migrants along the Migration topology until ctx is done. Reports still
+// queued then, such as the final reports of stopped islands, are crowned before
+// it returns.
+func (self *IslandEvolver) Interbreed(ctx context.Context) {
 	for {
-		if self.ChampionSize < 2 {
-			time.Sleep(1 * time.Second)
+		var report *PopulationReport
+		select {
+		case <-ctx.Done():
+			self.crownQueued()
+			return
+		case report = <-self.PopulationReportChan:
+		}
+		if len(report.SolutionList) == 0 {
 			continue
 		}
-		best := make(Champions, self.ChampionSize)
-		for x := 0; x < self.ChampionSize; x++ {
-			runtime.Gosched()
-			populationReport := <-self.PopulationReportChan
-			sort.Sort(populationReport)
-			champ := Champion{populationReport.SolutionList[0].Reward, make([]string, len(populationReport.SolutionList))}
-			for y := 0; y < len(populationReport.SolutionList); y++ {
-				champ.Programs[y] = populationReport.SolutionList[y].Program
+		self.crown(report)
+		self.migrate(report)
+	}
+}
+
+// crownQueued crowns the reports waiting in PopulationReportChan without blocking.
+func (self *IslandEvolver) crownQueued() {
+	for {
+		select {
+		case report := <-self.PopulationReportChan:
+			if len(report.SolutionList) > 0 {
+				self.crown(report)
 			}
-			best[x] = champ
+		default:
+			return
+		}
+	}
+}
+
+// crown publishes the best program of report if it beats every earlier champion.
+func (self *IslandEvolver) crown(report *PopulationReport) {
+	solutions := append(SolutionList{}, report.SolutionList...)
+	sort.Stable(solutions)
+	self.lock.Lock()
+	if len(self.Champions) > 0 && self.Champions[len(self.Champions)-1].Reward >= solutions[0].Reward {
+		self.lock.Unlock()
+		return
+	}
+	champ := Champion{solutions[0].Fitness, solutions.GetPrograms(), report.Id, report.Generation}
+	self.Champions = append(self.Champions, champ)
+	sinks := self.Sinks
+	self.lock.Unlock()
+	if err := sinks.Publish(champ); err != nil {
+		log.Printf("Publishing champion: %v", err)
+	}
+}
+
+func (self *IslandEvolver) migrate(report *PopulationReport) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if _, ok := self.inboxes[report.Id]; !ok {
+		return
+	}
+	self.reports[report.Id] = report
+	if !self.Migration.due(report) || self.Migration.Topology == nil {
+		return
+	}
+	islands := make([]int, 0, len(self.inboxes))
+	for id := range self.inboxes {
+		islands = append(islands, id)
+	}
+	sort.Ints(islands)
+	emigrants := self.Migration.emigrants(report)
+	for _, to := range self.Migration.Topology.Neighbours(self.random(), report.Id, islands) {
+		inbox, ok := self.inboxes[to]
+		if !ok {
+			continue
 		}
-		go func(champs Champions) {
-			sort.Sort(champs)
-			time.Sleep(time.Second)
-			self.InfluxBreeder <- champs[0].Programs
-			writeFile("bestProgram.vm", champs[0].Programs[0])
-		}(best)
-	}
-}
-
-func writeFile(name, data string) {
-	f, _ := os.Create(name)
-	w := bufio.NewWriter(f)
-	w.WriteString(data)
-	w.Flush()
-	f.Close()
-	cmd := exec.Command("git", "add .")
-	out, _ := cmd.Output()
-	log.Printf("Git: %v", string(out))
-	cmd = exec.Command("git", "commit -m \"Automated pushing best program so far\"")
-	out, _ = cmd.Output()
-	log.Printf("Git: %v", string(out))
-	cmd = exec.Command("git", "push")
-	out, _ = cmd.Output()
-	log.Printf("Git: %v", string(out))
+		immigrants := self.Migration.admit(self.reports[to], emigrants)
+		if len(immigrants) == 0 {
+			continue
+		}
+		select {
+		case inbox <- immigrants.GetPrograms():
+		default:
+		}
+	}
 }
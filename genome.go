@@ -0,0 +1,279 @@
+package goevolve
+
+import (
+	"github.com/tsavo/GoVirtual"
+	"strconv"
+	"strings"
+)
+
+// Argument is one operand of an instruction: a label such as ":start", a
+// register reference such as "#3" or a literal such as "42". Type is the
+// govirtual argument type when the instruction is known.
+type Argument struct {
+	Type  string
+	Label string
+	Value int
+	Ref   bool
+}
+
+func ParseArgument(s string) Argument {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "#") {
+		if n, err := strconv.Atoi(s[1:]); err == nil {
+			return Argument{Value: n, Ref: true}
+		}
+	} else if n, err := strconv.Atoi(s); err == nil {
+		return Argument{Value: n}
+	}
+	return Argument{Label: s}
+}
+
+func (arg Argument) IsLabel() bool {
+	return arg.Label != ""
+}
+
+func (arg Argument) String() string {
+	switch {
+	case arg.IsLabel():
+		return arg.Label
+	case arg.Ref:
+		return "#" + strconv.Itoa(arg.Value)
+	default:
+		return strconv.Itoa(arg.Value)
+	}
+}
+
+// Gene is one line of a program: either a label definition or an instruction.
+type Gene struct {
+	Label     string
+	Name      string
+	Arguments []Argument
+}
+
+func (gene Gene) IsLabel() bool {
+	return gene.Label != ""
+}
+
+func (gene Gene) String() string {
+	if gene.IsLabel() {
+		return gene.Label
+	}
+	if len(gene.Arguments) == 0 {
+		return gene.Name
+	}
+	return gene.Name + " " + joinArguments(gene.Arguments)
+}
+
+func joinArguments(arguments []Argument) string {
+	args := make([]string, len(arguments))
+	for x, arg := range arguments {
+		args[x] = arg.String()
+	}
+	return strings.Join(args, ",")
+}
+
+func (gene Gene) Clone() Gene {
+	args := make([]Argument, len(gene.Arguments))
+	copy(args, gene.Arguments)
+	gene.Arguments = args
+	return gene
+}
+
+// Genome is the structured form of a program. ParseGenome and String convert
+// losslessly to and from the newline separated text format.
+type Genome []Gene
+
+func ParseGenome(program string, is *govirtual.InstructionSet) Genome {
+	genome := make(Genome, 0)
+	for _, line := range strings.Split(program, "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			genome = append(genome, Gene{Label: strings.Fields(line)[0]})
+			continue
+		}
+		parts := strings.SplitN(line, " ", 2)
+		gene := Gene{Name: parts[0], Arguments: make([]Argument, 0)}
+		if len(parts) > 1 && len(strings.TrimSpace(parts[1])) > 0 {
+			for _, arg := range strings.Split(parts[1], ",") {
+				gene.Arguments = append(gene.Arguments, ParseArgument(arg))
+			}
+		}
+		if i := LookupInstruction(is, gene.Name); i != nil {
+			for x := 0; x < len(gene.Arguments) && x < len(i.Arguments); x++ {
+				gene.Arguments[x].Type = i.Arguments[x].Type
+			}
+		}
+		genome = append(genome, gene)
+	}
+	return genome
+}
+
+func (genome Genome) String() string {
+	out := ""
+	for _, gene := range genome {
+		out += gene.String() + "\n"
+	}
+	return out
+}
+
+func (genome Genome) Clone() Genome {
+	out := make(Genome, len(genome))
+	for x, gene := range genome {
+		out[x] = gene.Clone()
+	}
+	return out
+}
+
+// Labels returns the labels defined by the genome in program order.
+func (genome Genome) Labels() []string {
+	labels := make([]string, 0)
+	for _, gene := range genome {
+		if gene.IsLabel() {
+			labels = append(labels, gene.Label)
+		}
+	}
+	return labels
+}
+
+// Compact drops every definition of a label after the first.
+func (genome Genome) Compact() Genome {
+	out := make(Genome, 0, len(genome))
+	seen := make(map[string]bool)
+	for _, gene := range genome {
+		if gene.IsLabel() {
+			if seen[gene.Label] {
+				continue
+			}
+			seen[gene.Label] = true
+		}
+		out = append(out, gene)
+	}
+	return out
+}
+
+func LookupInstruction(is *govirtual.InstructionSet, name string) *govirtual.Instruction {
+	if is == nil {
+		return nil
+	}
+	for _, i := range *is {
+		if i.Name == name {
+			return i
+		}
+	}
+	return nil
+}
+
+// randomInstruction picks an instruction that can start a line.
+func randomInstruction(rng *SafeRNG, is *govirtual.InstructionSet) *govirtual.Instruction {
+	i := (*is)[rng.Int()%len(*is)]
+	for i.Infix || strings.HasPrefix(i.Name, ":") {
+		i = (*is)[rng.Int()%len(*is)]
+	}
+	return i
+}
+
+func randomGene(rng *SafeRNG, gen ArgumentGenerator, is *govirtual.InstructionSet, labels []string) Gene {
+	i := randomInstruction(rng, is)
+	return Gene{Name: i.Name, Arguments: generateArguments(rng, gen, i, nil, labels)}
+}
+
+// newLabel returns a label not yet in labels.
+func newLabel(rng *SafeRNG, labels []string) string {
+	for {
+		label := ":" + USDict.RandomWordFrom(rng)
+		taken := false
+		for _, l := range labels {
+			taken = taken || l == label
+		}
+		if !taken {
+			return label
+		}
+	}
+}
+
+// Blocks splits the genome before every label definition. The first block holds
+// any instructions ahead of the first label and may be empty.
+func (genome Genome) Blocks() []Genome {
+	blocks := []Genome{Genome{}}
+	for _, gene := range genome {
+		if gene.IsLabel() {
+			blocks = append(blocks, Genome{})
+		}
+		blocks[len(blocks)-1] = append(blocks[len(blocks)-1], gene)
+	}
+	return blocks
+}
+
+// BasicBlocks splits the genome before every definition of one of labels and
+// after every instruction whose name starts with one of terminators. Labels are
+// matched with or without their leading colon. There is always at least one block.
+func (genome Genome) BasicBlocks(labels, terminators []string) []Genome {
+	starts := make(map[string]bool)
+	for _, label := range labels {
+		starts[strings.TrimPrefix(label, ":")] = true
+	}
+	blocks := []Genome{Genome{}}
+	for _, gene := range genome {
+		last := len(blocks) - 1
+		if gene.IsLabel() && starts[strings.TrimPrefix(gene.Label, ":")] && len(blocks[last]) > 0 {
+			blocks = append(blocks, Genome{})
+			last++
+		}
+		blocks[last] = append(blocks[last], gene)
+		for _, prefix := range terminators {
+			if !gene.IsLabel() && strings.HasPrefix(gene.Name, prefix) {
+				blocks = append(blocks, Genome{})
+				break
+			}
+		}
+	}
+	if len(blocks) > 1 && len(blocks[len(blocks)-1]) == 0 {
+		blocks = blocks[:len(blocks)-1]
+	}
+	return blocks
+}
+
+// Repair drops repeated label definitions and points every label argument that
+// names an undefined label at a label the genome does define.
+func (genome Genome) Repair(rng *SafeRNG) Genome {
+	out := genome.Clone().Compact()
+	labels := out.Labels()
+	defined := make(map[string]bool)
+	for _, label := range labels {
+		defined[label] = true
+	}
+	for _, gene := range out {
+		for _, arg := range gene.Arguments {
+			if len(labels) == 0 && strings.HasPrefix(arg.Label, ":") {
+				labels = append(labels, ":start")
+				defined[":start"] = true
+				out = append(Genome{Gene{Label: ":start"}}, out...)
+			}
+		}
+	}
+	for x, gene := range out {
+		for y, arg := range gene.Arguments {
+			if strings.HasPrefix(arg.Label, ":") && !defined[arg.Label] {
+				out[x].Arguments[y].Label = labels[rng.Int()%len(labels)]
+			}
+		}
+	}
+	return out
+}
+
+// renameLabel renames a label definition and every reference to it.
+func (genome Genome) renameLabel(from, to string) {
+	for x := range genome {
+		if genome[x].Label == from {
+			genome[x].Label = to
+		}
+		for y := range genome[x].Arguments {
+			if genome[x].Arguments[y].Label == from {
+				genome[x].Arguments[y].Label = to
+			}
+		}
+	}
+}
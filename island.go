@@ -0,0 +1,147 @@
+package goevolve
+
+import (
+	"github.com/tsavo/GoVirtual"
+	"log"
+	"sync"
+)
+
+// IslandConfig is everything an island was created with. Islands of the same
+// IslandEvolver may use entirely different configurations. Store, when set, is
+// the island's cache of evaluated programs; islands given the same Store share
+// their fitness scores, so they must evaluate programs the same way. When it is
+// not set the island caches in its own namespace of the evolver's Store.
+type IslandConfig struct {
+	Heap                 *govirtual.Memory
+	RegisterLength       int
+	InstructionSet       *govirtual.InstructionSet
+	TerminationCondition govirtual.TerminationCondition
+	Breeder              Breeder
+	Evaluator            Evaluator
+	Selector             Selector
+	Store                SolutionStore
+}
+
+// Island is the handle to one island of an IslandEvolver. Population may be
+// tuned further (Workers, Replacement, Optimizer...) before the evolver starts.
+// Remote islands, run by a Worker, have no Population or Config and Remote holds
+// the Worker's address.
+type Island struct {
+	Id         int
+	Config     IslandConfig
+	Population *Population
+	Remote     string
+	evolver    *IslandEvolver
+	remote     *peer
+	done       chan bool
+	finished   sync.Once
+	paused     bool
+}
+
+// Report returns the island's latest report, or nil before its first generation.
+func (island *Island) Report() *PopulationReport {
+	if island.remote == nil {
+		return island.Population.Report()
+	}
+	island.evolver.lock.Lock()
+	defer island.evolver.lock.Unlock()
+	return island.evolver.reports[island.Id]
+}
+
+// Stats returns the statistics of the island's latest generation, or nil before its first generation.
+func (island *Island) Stats() *GenerationStats {
+	report := island.Report()
+	if report == nil {
+		return nil
+	}
+	stats := report.GenerationStats
+	return &stats
+}
+
+func (island *Island) Pause() {
+	if island.remote == nil {
+		island.Population.Pause()
+		return
+	}
+	island.control(PauseMessage, true)
+}
+
+func (island *Island) Resume() {
+	if island.remote == nil {
+		island.Population.Resume()
+		return
+	}
+	island.control(ResumeMessage, false)
+}
+
+func (island *Island) Paused() bool {
+	if island.remote == nil {
+		return island.Population.Paused()
+	}
+	island.evolver.lock.Lock()
+	defer island.evolver.lock.Unlock()
+	return island.paused
+}
+
+func (island *Island) control(kind MessageKind, paused bool) {
+	if err := island.remote.send(Message{Kind: kind, Island: island.Id}); err != nil {
+		log.Printf("#%d: %v", island.Id, err)
+		return
+	}
+	island.evolver.lock.Lock()
+	island.paused = paused
+	island.evolver.lock.Unlock()
+}
+
+// halt stops or drains the island and waits for it to finish.
+func (island *Island) halt(drain bool) {
+	if island.remote == nil {
+		if drain {
+			island.Population.Drain()
+		} else {
+			island.Population.Stop()
+		}
+		return
+	}
+	kind := StopMessage
+	if drain {
+		kind = DrainMessage
+	}
+	if island.remote.send(Message{Kind: kind, Island: island.Id}) == nil {
+		island.wait()
+	}
+}
+
+func (island *Island) wait() {
+	if island.remote == nil {
+		island.Population.Wait()
+		return
+	}
+	<-island.done
+}
+
+// finish marks a remote island as stopped.
+func (island *Island) finish() {
+	island.finished.Do(func() {
+		close(island.done)
+	})
+}
+
+// Remove stops the island and takes it out of the evolver. Champions it
+// produced are kept.
+func (island *Island) Remove() {
+	island.evolver.RemoveIsland(island.Id)
+}
+
+// Champions returns the evolver's champions that came from this island.
+func (island *Island) Champions() Champions {
+	island.evolver.lock.Lock()
+	defer island.evolver.lock.Unlock()
+	champions := make(Champions, 0)
+	for _, champion := range island.evolver.Champions {
+		if champion.Island == island.Id {
+			champions = append(champions, champion)
+		}
+	}
+	return champions
+}
@@ -3,6 +3,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"github.com/gorilla/websocket"
 	"github.com/tsavo/GoEvolve"
@@ -191,10 +192,10 @@ type FlappyEvaluator struct {
 	reward int64
 }
 
-func (eval *FlappyEvaluator) Evaluate(p *govirtual.Processor) int64 {
-	x := eval.reward - (p.Cost() / 10000)
+func (eval *FlappyEvaluator) Evaluate(p *govirtual.Processor) goevolve.Fitness {
+	x := eval.reward - int64(p.Cost()/10000)
 	eval.reward = 0
-	return x
+	return goevolve.Fitness{Reward: float64(x)}
 }
 
 type FlappyGenerator struct {
@@ -300,7 +301,15 @@ func loadProgram(projectName string, id int) govirtual.Program {
 var FlappyIsland *goevolve.IslandEvolver
 
 func main() {
-	FlappyIsland = goevolve.NewIslandEvolver(3)
+	FlappyIsland = goevolve.NewIslandEvolver()
+	store, err := goevolve.OpenFileStore("SolutionCache.gob", 60*time.Second)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer store.Close()
+	FlappyIsland.Store = store
+	FlappyIsland.AddSink(goevolve.NewFileSink("bestProgram.vm"))
+	FlappyIsland.Start(context.Background())
 	go h.run()
 	go func() {
 		http.HandleFunc("/ws", wsHandler)
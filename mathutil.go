@@ -23,41 +23,68 @@ func Now() int {
 }
 
 type SafeRNG struct {
-	rng *rand.Rand
+	rng  *rand.Rand
+	seed int64
+	lock sync.Mutex
 }
 
-var (
-	rng  SafeRNG
-	lock sync.Mutex
-)
+var rng = NewRNG(time.Now().UnixNano())
+
+func NewRNG(seed int64) *SafeRNG {
+	r := &SafeRNG{rng: rand.New(mt19937.New())}
+	r.Seed(seed)
+	return r
+}
+
+func (rng *SafeRNG) Seed(seed int64) {
+	rng.lock.Lock()
+	defer rng.lock.Unlock()
+	rng.seed = seed
+	rng.rng.Seed(seed)
+}
 
-func init() {
-	rng = SafeRNG{rand.New(mt19937.New())}
-	rng.rng.Seed(time.Now().UnixNano())
-	lock = sync.Mutex{}
+// CurrentSeed returns the seed the generator was last started from.
+func (rng *SafeRNG) CurrentSeed() int64 {
+	rng.lock.Lock()
+	defer rng.lock.Unlock()
+	return rng.seed
+}
+
+// Derive returns an independent generator for stream id. It depends only on the
+// current seed and id, never on how much has been drawn, so streams handed out to
+// goroutines are reproducible whatever order they run in.
+func (rng *SafeRNG) Derive(id int64) *SafeRNG {
+	return NewRNG(int64(splitMix64(uint64(rng.CurrentSeed()) ^ splitMix64(uint64(id)))))
+}
+
+func splitMix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
 }
 
 func (rng *SafeRNG) Int() int {
-	lock.Lock()
-	defer lock.Unlock()
+	rng.lock.Lock()
+	defer rng.lock.Unlock()
 	return rng.rng.Int()
 }
 
 func (rng *SafeRNG) Int63() int64 {
-	lock.Lock()
-	defer lock.Unlock()
+	rng.lock.Lock()
+	defer rng.lock.Unlock()
 	return rng.rng.Int63()
 }
 
 func (rng *SafeRNG) Float64() float64 {
-	lock.Lock()
-	defer lock.Unlock()
+	rng.lock.Lock()
+	defer rng.lock.Unlock()
 	return rng.rng.Float64()
 }
 
 func (rng *SafeRNG) SmallInt() int {
-	lock.Lock()
-	defer lock.Unlock()
+	rng.lock.Lock()
+	defer rng.lock.Unlock()
 	x := rng.rng.Intn(10000)
 	//	if rng.rng.Float64() < 0.5 {
 	//		x *= -1
@@ -65,6 +92,44 @@ func (rng *SafeRNG) SmallInt() int {
 	return x
 }
 
+// Randomized is implemented by breeders and selectors that can be handed a random
+// stream by their Population. A component whose RNG was set explicitly keeps it.
+type Randomized interface {
+	InheritRNG(r *SafeRNG)
+}
+
+// RNGSource is embedded by components that draw random numbers. With no RNG set
+// they fall back to the package generator. An inherited RNG is replaced by the
+// next one inherited, so a Population can hand out fresh streams every generation.
+type RNGSource struct {
+	RNG       *SafeRNG
+	inherited *SafeRNG
+}
+
+func (source *RNGSource) InheritRNG(r *SafeRNG) {
+	if source.RNG == nil || source.RNG == source.inherited {
+		source.RNG = r
+		source.inherited = r
+	}
+}
+
+func (source *RNGSource) SeedRNG(seed int64) {
+	source.RNG = NewRNG(seed)
+}
+
+func (source RNGSource) random() *SafeRNG {
+	if source.RNG == nil {
+		return rng
+	}
+	return source.RNG
+}
+
+func inheritRNG(x interface{}, r *SafeRNG) {
+	if randomized, ok := x.(Randomized); ok {
+		randomized.InheritRNG(r)
+	}
+}
+
 func Max8(left, right int8) int8 {
 	if left > right {
 		return left
@@ -0,0 +1,138 @@
+package goevolve
+
+import (
+	"sort"
+)
+
+// Topology decides which islands receive the migrants an island sends out.
+// islands lists every island id in ascending order.
+type Topology interface {
+	Neighbours(rng *SafeRNG, from int, islands []int) []int
+}
+
+type TopologyFunc func(rng *SafeRNG, from int, islands []int) []int
+
+func (f TopologyFunc) Neighbours(rng *SafeRNG, from int, islands []int) []int {
+	return f(rng, from, islands)
+}
+
+// RingTopology sends migrants to the next island, wrapping around.
+type RingTopology struct{}
+
+func (RingTopology) Neighbours(rng *SafeRNG, from int, islands []int) []int {
+	for x, id := range islands {
+		if id == from && len(islands) > 1 {
+			return []int{islands[(x+1)%len(islands)]}
+		}
+	}
+	return nil
+}
+
+// FullyConnected sends migrants to every other island.
+type FullyConnected struct{}
+
+func (FullyConnected) Neighbours(rng *SafeRNG, from int, islands []int) []int {
+	out := make([]int, 0, len(islands))
+	for _, id := range islands {
+		if id != from {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+// StarTopology connects every island to the Hub and nothing else.
+type StarTopology struct {
+	Hub int
+}
+
+func (star StarTopology) Neighbours(rng *SafeRNG, from int, islands []int) []int {
+	if from == star.Hub {
+		return FullyConnected{}.Neighbours(rng, from, islands)
+	}
+	return []int{star.Hub}
+}
+
+// RandomTopology sends migrants to Degree islands drawn afresh for every migration.
+type RandomTopology struct {
+	Degree int
+}
+
+func (random RandomTopology) Neighbours(rng *SafeRNG, from int, islands []int) []int {
+	others := FullyConnected{}.Neighbours(rng, from, islands)
+	for x := len(others) - 1; x > 0; x-- {
+		y := rng.Int() % (x + 1)
+		others[x], others[y] = others[y], others[x]
+	}
+	return others[:Min(Max(random.Degree, 1), len(others))]
+}
+
+// ImmigrantPolicy chooses which arriving migrants an island accepts. target is
+// the receiving island's latest report and may be nil.
+type ImmigrantPolicy interface {
+	Admit(target *PopulationReport, migrants SolutionList) SolutionList
+}
+
+// AcceptAll admits every migrant.
+type AcceptAll struct{}
+
+func (AcceptAll) Admit(target *PopulationReport, migrants SolutionList) SolutionList {
+	return migrants
+}
+
+// AcceptBetter admits migrants that beat the receiving island's median reward.
+type AcceptBetter struct{}
+
+func (AcceptBetter) Admit(target *PopulationReport, migrants SolutionList) SolutionList {
+	if target == nil {
+		return migrants
+	}
+	out := make(SolutionList, 0, len(migrants))
+	for _, migrant := range migrants {
+		if migrant.Reward > target.Median {
+			out = append(out, migrant)
+		}
+	}
+	return out
+}
+
+// Migration configures how islands exchange programs. Every Interval
+// generations an island sends up to Migrants programs, chosen by Emigrants (the
+// best ones when nil), to its Topology neighbours, whose Immigrants policy
+// (AcceptAll when nil) filters them.
+type Migration struct {
+	Topology   Topology
+	Interval   int
+	Migrants   int
+	Emigrants  Selector
+	Immigrants ImmigrantPolicy
+}
+
+func DefaultMigration() Migration {
+	return Migration{Topology: RingTopology{}, Interval: 1, Migrants: 10}
+}
+
+func (m Migration) due(report *PopulationReport) bool {
+	return report.Generation%Max(m.Interval, 1) == 0
+}
+
+func (m Migration) emigrants(report *PopulationReport) SolutionList {
+	candidates := append(SolutionList{}, report.SolutionList...)
+	if m.Emigrants != nil {
+		selected := m.Emigrants.Select(&candidates)
+		if selected == nil {
+			return nil
+		}
+		candidates = *selected
+	} else {
+		sort.Stable(candidates)
+	}
+	return candidates[:Min(m.Migrants, len(candidates))]
+}
+
+func (m Migration) admit(target *PopulationReport, migrants SolutionList) SolutionList {
+	if m.Immigrants == nil {
+		return migrants
+	}
+	return m.Immigrants.Admit(target, migrants)
+}
@@ -0,0 +1,209 @@
+package goevolve
+
+import (
+	"github.com/tsavo/GoVirtual"
+	"math"
+)
+
+// Names of the built-in mutation operators.
+const (
+	InsertMutation      = "insert"
+	DeleteMutation      = "delete"
+	ReplaceMutation     = "replace"
+	PerturbMutation     = "perturb"
+	DuplicateMutation   = "duplicate"
+	SwapMutation        = "swap"
+	AddLabelMutation    = "addLabel"
+	RemoveLabelMutation = "removeLabel"
+)
+
+// MutationFunc applies one kind of mutation to a genome. rate is the chance of
+// mutating each line (or block, for block operators).
+type MutationFunc func(breeder MutationBreeder, rng *SafeRNG, rate float64, genome Genome) Genome
+
+type MutationOperator struct {
+	Name   string
+	Rate   float64
+	Mutate MutationFunc
+}
+
+// DefaultMutationOperators returns every built-in operator with rates scaled from
+// a single mutation chance.
+func DefaultMutationOperators(chance float64) []MutationOperator {
+	return []MutationOperator{
+		{InsertMutation, chance * chance, InsertInstructions},
+		{DeleteMutation, chance * 0.1, DeleteInstructions},
+		{ReplaceMutation, chance * 0.5, ReplaceOpcodes},
+		{PerturbMutation, chance * 0.5, PerturbArguments},
+		{DuplicateMutation, chance * 0.1, DuplicateBlocks},
+		{SwapMutation, chance * 0.1, SwapLines},
+		{AddLabelMutation, chance * 0.1, AddLabels},
+		{RemoveLabelMutation, chance * 0.1, RemoveLabels},
+	}
+}
+
+// MutationBreeder copies its seeds and runs every operator over each copy in
+// turn. InsertLength caps the run of random instructions InsertInstructions adds.
+// With an Adaptation the operator rates are scaled by it as the run progresses.
+type MutationBreeder struct {
+	PopulationSize int
+	Operators      []MutationOperator
+	InsertLength   int
+	Adaptation     *RateAdapter
+	*govirtual.InstructionSet
+	RNGSource
+	ArgumentSource
+}
+
+func NewMutationBreeder(popSize int, mutationChance float64, is *govirtual.InstructionSet) *MutationBreeder {
+	return &MutationBreeder{PopulationSize: popSize, Operators: DefaultMutationOperators(mutationChance), InsertLength: 10, InstructionSet: is}
+}
+
+// SetRate changes the rate of the named operator; a rate of 0 disables it.
+func (breeder *MutationBreeder) SetRate(name string, rate float64) bool {
+	for x := range breeder.Operators {
+		if breeder.Operators[x].Name == name {
+			breeder.Operators[x].Rate = rate
+			return true
+		}
+	}
+	return false
+}
+
+func (breeder MutationBreeder) Rate(name string) float64 {
+	for _, op := range breeder.Operators {
+		if op.Name == name {
+			return op.Rate
+		}
+	}
+	return 0
+}
+
+func (breeder MutationBreeder) Adapt(report *PopulationReport) {
+	if breeder.Adaptation != nil {
+		breeder.Adaptation.Adapt(report)
+	}
+}
+
+func (breeder MutationBreeder) Breed(seeds []string) []string {
+	if len(seeds) == 0 {
+		return nil
+	}
+	rng := breeder.random()
+	out := make([]string, breeder.PopulationSize)
+	for x := 0; x < breeder.PopulationSize; x++ {
+		genome := ParseGenome(seeds[x%len(seeds)], breeder.InstructionSet)
+		for _, op := range breeder.Operators {
+			if rate := op.Rate * breeder.Adaptation.Scale(op.Name); rate > 0 {
+				genome = op.Mutate(breeder, rng, math.Min(rate, 1), genome)
+			}
+		}
+		out[x] = genome.Repair(rng).String()
+	}
+	return out
+}
+
+// InsertInstructions adds a run of up to InsertLength random instructions after a line.
+func InsertInstructions(breeder MutationBreeder, rng *SafeRNG, rate float64, genome Genome) Genome {
+	labels := genome.Labels()
+	out := make(Genome, 0, len(genome))
+	for _, gene := range genome {
+		out = append(out, gene)
+		if rng.Float64() < rate {
+			for r := rng.Int()%Max(breeder.InsertLength, 1) + 1; r > 0; r-- {
+				out = append(out, randomGene(rng, breeder.arguments(), breeder.InstructionSet, labels))
+			}
+		}
+	}
+	return out
+}
+
+func DeleteInstructions(breeder MutationBreeder, rng *SafeRNG, rate float64, genome Genome) Genome {
+	out := make(Genome, 0, len(genome))
+	for _, gene := range genome {
+		if gene.IsLabel() || rng.Float64() >= rate {
+			out = append(out, gene)
+		}
+	}
+	return out
+}
+
+// ReplaceOpcodes swaps an instruction for a random one, reusing its arguments where the arity allows.
+func ReplaceOpcodes(breeder MutationBreeder, rng *SafeRNG, rate float64, genome Genome) Genome {
+	labels := genome.Labels()
+	out := genome.Clone()
+	for x, gene := range out {
+		if !gene.IsLabel() && rng.Float64() < rate {
+			i := randomInstruction(rng, breeder.InstructionSet)
+			out[x] = Gene{Name: i.Name, Arguments: generateArguments(rng, breeder.arguments(), i, gene.Arguments, labels)}
+		}
+	}
+	return out
+}
+
+func PerturbArguments(breeder MutationBreeder, rng *SafeRNG, rate float64, genome Genome) Genome {
+	labels := genome.Labels()
+	out := genome.Clone()
+	for x, gene := range out {
+		if gene.IsLabel() || rng.Float64() >= rate {
+			continue
+		}
+		if i := LookupInstruction(breeder.InstructionSet, gene.Name); i != nil {
+			out[x].Arguments = generateArguments(rng, breeder.arguments(), i, gene.Arguments, labels)
+		}
+	}
+	return out
+}
+
+// DuplicateBlocks repeats a labelled block right after itself under a new label.
+func DuplicateBlocks(breeder MutationBreeder, rng *SafeRNG, rate float64, genome Genome) Genome {
+	labels := genome.Labels()
+	out := Genome{}
+	for _, block := range genome.Blocks() {
+		out = append(out, block...)
+		if len(block) > 0 && block[0].IsLabel() && rng.Float64() < rate {
+			copied := block.Clone()
+			label := newLabel(rng, labels)
+			labels = append(labels, label)
+			copied[0].Label = label
+			out = append(out, copied...)
+		}
+	}
+	return out
+}
+
+func SwapLines(breeder MutationBreeder, rng *SafeRNG, rate float64, genome Genome) Genome {
+	out := genome.Clone()
+	for x := range out {
+		if rng.Float64() < rate {
+			y := rng.Int() % len(out)
+			out[x], out[y] = out[y], out[x]
+		}
+	}
+	return out
+}
+
+func AddLabels(breeder MutationBreeder, rng *SafeRNG, rate float64, genome Genome) Genome {
+	labels := genome.Labels()
+	out := make(Genome, 0, len(genome))
+	for _, gene := range genome {
+		if rng.Float64() < rate {
+			label := newLabel(rng, labels)
+			labels = append(labels, label)
+			out = append(out, Gene{Label: label})
+		}
+		out = append(out, gene)
+	}
+	return out
+}
+
+// RemoveLabels drops label definitions; Breed repairs references to them afterwards.
+func RemoveLabels(breeder MutationBreeder, rng *SafeRNG, rate float64, genome Genome) Genome {
+	out := make(Genome, 0, len(genome))
+	for _, gene := range genome {
+		if !gene.IsLabel() || rng.Float64() >= rate {
+			out = append(out, gene)
+		}
+	}
+	return out
+}
@@ -0,0 +1,206 @@
+package goevolve
+
+import (
+	"math"
+	"sort"
+	"sync"
+)
+
+// Archived is implemented by selectors that score novelty against a
+// Population's NoveltyArchive.
+type Archived interface {
+	UseArchive(archive *NoveltyArchive)
+}
+
+func useArchive(x interface{}, archive *NoveltyArchive) {
+	if a, ok := x.(Archived); ok {
+		a.UseArchive(archive)
+	}
+}
+
+// NoveltyArchive remembers behaviors that were novel when they were seen. A
+// behavior is added when its novelty, the mean distance to its K nearest
+// neighbours in the generation and the archive, reaches Threshold. Once the
+// archive holds Capacity behaviors the oldest are forgotten; zero keeps them all.
+type NoveltyArchive struct {
+	K         int
+	Threshold float64
+	Capacity  int
+	behaviors [][]float64
+	lock      sync.Mutex
+}
+
+func NewNoveltyArchive(k int, threshold float64, capacity int) *NoveltyArchive {
+	return &NoveltyArchive{K: k, Threshold: threshold, Capacity: capacity}
+}
+
+func (archive *NoveltyArchive) Len() int {
+	if archive == nil {
+		return 0
+	}
+	archive.lock.Lock()
+	defer archive.lock.Unlock()
+	return len(archive.behaviors)
+}
+
+// Behaviors returns a copy of the archived behaviors, oldest first. A nil archive is empty.
+func (archive *NoveltyArchive) Behaviors() [][]float64 {
+	if archive == nil {
+		return nil
+	}
+	archive.lock.Lock()
+	defer archive.lock.Unlock()
+	return append([][]float64{}, archive.behaviors...)
+}
+
+// Update archives the behaviors of solutions that are novel enough.
+func (archive *NoveltyArchive) Update(solutions SolutionList) {
+	novelty := NoveltyScores(solutions, archive.K, archive)
+	archive.lock.Lock()
+	defer archive.lock.Unlock()
+	for x, solution := range solutions {
+		if novelty[x] >= archive.Threshold {
+			archive.behaviors = append(archive.behaviors, behaviorOf(solution))
+		}
+	}
+	if archive.Capacity > 0 && len(archive.behaviors) > archive.Capacity {
+		archive.behaviors = append([][]float64{}, archive.behaviors[len(archive.behaviors)-archive.Capacity:]...)
+	}
+}
+
+// behaviorOf falls back on a solution's objectives, then its reward, when the
+// Evaluator reports no Behavior.
+func behaviorOf(s *Solution) []float64 {
+	if len(s.Behavior) == 0 {
+		return objectivesOf(s)
+	}
+	return s.Behavior
+}
+
+// BehaviorDistance is the Euclidean distance between two behaviors. Missing
+// dimensions of the shorter one count as zero.
+func BehaviorDistance(a, b []float64) float64 {
+	if len(a) < len(b) {
+		a, b = b, a
+	}
+	sum := 0.0
+	for i := range a {
+		d := a[i]
+		if i < len(b) {
+			d -= b[i]
+		}
+		sum += d * d
+	}
+	return math.Sqrt(sum)
+}
+
+// NoveltyScores returns, for every solution, the mean distance from its behavior
+// to its k nearest neighbours among the other solutions and the archive.
+func NoveltyScores(solutions SolutionList, k int, archive *NoveltyArchive) []float64 {
+	archived := archive.Behaviors()
+	scores := make([]float64, len(solutions))
+	for x, solution := range solutions {
+		behavior := behaviorOf(solution)
+		distances := make([]float64, 0, len(solutions)+len(archived))
+		for y, other := range solutions {
+			if x != y {
+				distances = append(distances, BehaviorDistance(behavior, behaviorOf(other)))
+			}
+		}
+		for _, other := range archived {
+			distances = append(distances, BehaviorDistance(behavior, other))
+		}
+		sort.Float64s(distances)
+		n := Min(Max(k, 1), len(distances))
+		for _, d := range distances[:n] {
+			scores[x] += d
+		}
+		if n > 0 {
+			scores[x] /= float64(n)
+		}
+	}
+	return scores
+}
+
+// NoveltySelector keeps the Keep solutions with the most novel behaviors,
+// ignoring Reward. Archive is supplied by the Population unless already set.
+type NoveltySelector struct {
+	Keep, K int
+	Archive *NoveltyArchive
+}
+
+func Novelty(keep, k int) *NoveltySelector {
+	return &NoveltySelector{Keep: keep, K: k}
+}
+
+func (sel *NoveltySelector) UseArchive(archive *NoveltyArchive) {
+	if sel.Archive == nil {
+		sel.Archive = archive
+	}
+}
+
+func (sel NoveltySelector) Select(s *SolutionList) *SolutionList {
+	return keepHighest(*s, NoveltyScores(*s, sel.K, sel.Archive), sel.Keep)
+}
+
+// NoveltyFitnessSelector keeps the Keep solutions with the highest blend of
+// novelty and Reward, both rescaled to [0, 1] across the generation. Weight is
+// the share given to novelty.
+type NoveltyFitnessSelector struct {
+	Keep, K int
+	Weight  float64
+	Archive *NoveltyArchive
+}
+
+func NoveltyAndFitness(keep, k int, weight float64) *NoveltyFitnessSelector {
+	return &NoveltyFitnessSelector{Keep: keep, K: k, Weight: weight}
+}
+
+func (sel *NoveltyFitnessSelector) UseArchive(archive *NoveltyArchive) {
+	if sel.Archive == nil {
+		sel.Archive = archive
+	}
+}
+
+func (sel NoveltyFitnessSelector) Select(s *SolutionList) *SolutionList {
+	novelty := rescale(NoveltyScores(*s, sel.K, sel.Archive))
+	rewards := make([]float64, len(*s))
+	for x, solution := range *s {
+		rewards[x] = solution.Reward
+	}
+	rewards = rescale(rewards)
+	scores := make([]float64, len(*s))
+	for x := range scores {
+		scores[x] = sel.Weight*novelty[x] + (1-sel.Weight)*rewards[x]
+	}
+	return keepHighest(*s, scores, sel.Keep)
+}
+
+// rescale maps values linearly onto [0, 1]. Equal values all map to 0.
+func rescale(values []float64) []float64 {
+	low, high := math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		low, high = math.Min(low, v), math.Max(high, v)
+	}
+	out := make([]float64, len(values))
+	for x, v := range values {
+		if high > low {
+			out[x] = (v - low) / (high - low)
+		}
+	}
+	return out
+}
+
+// keepHighest returns the keep solutions with the highest scores, best first.
+func keepHighest(solutions SolutionList, scores []float64, keep int) *SolutionList {
+	index := make([]int, len(solutions))
+	for i := range index {
+		index[i] = i
+	}
+	sort.SliceStable(index, func(i, j int) bool { return scores[index[i]] > scores[index[j]] })
+	out := make(SolutionList, 0, keep)
+	for _, i := range index[:Min(Max(keep, 0), len(index))] {
+		out = append(out, solutions[i])
+	}
+	return &out
+}
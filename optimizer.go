@@ -0,0 +1,89 @@
+package goevolve
+
+import (
+	"context"
+	"sort"
+)
+
+// ConstantOptimizer tunes the literal arguments of a Population's best Solutions
+// by hill climbing before they are bred. Each of the Elites best solutions gets
+// Iterations candidate evaluations; a candidate nudges one literal by up to Step,
+// which doubles after an improvement and halves after a failure.
+type ConstantOptimizer struct {
+	Elites, Iterations, Step int
+	RNGSource
+}
+
+func NewConstantOptimizer(elites, iterations int) *ConstantOptimizer {
+	return &ConstantOptimizer{Elites: elites, Iterations: iterations, Step: 16}
+}
+
+type literal struct {
+	gene, arg int
+}
+
+// Optimize returns the best variant of solution found; evaluate scores a program.
+func (opt *ConstantOptimizer) Optimize(solution *Solution, evaluate func(program string) *Solution) *Solution {
+	rng := opt.random()
+	best := solution
+	genome := ParseGenome(solution.Program, nil)
+	literals := make([]literal, 0)
+	for x, gene := range genome {
+		for y, arg := range gene.Arguments {
+			if !arg.IsLabel() && !arg.Ref {
+				literals = append(literals, literal{x, y})
+			}
+		}
+	}
+	if len(literals) == 0 {
+		return best
+	}
+	step := Max(opt.Step, 1)
+	for i := 0; i < opt.Iterations; i++ {
+		l := literals[rng.Int()%len(literals)]
+		candidate := genome.Clone()
+		delta := rng.Int()%step + 1
+		if rng.Float64() < 0.5 {
+			delta = -delta
+		}
+		candidate[l.gene].Arguments[l.arg].Value += delta
+		if tried := evaluate(candidate.String()); tried.Reward > best.Reward {
+			best, genome = tried, candidate
+			step *= 2
+		} else {
+			step = Max(step/2, 1)
+		}
+	}
+	return best
+}
+
+// optimize replaces the elite solutions with their tuned variants, which are
+// cached in the Store. It returns how many variants it scored, hits of them from the Store.
+func (s *Population) optimize(ctx context.Context, solutions SolutionList) (evaluations, hits int) {
+	if s.Optimizer == nil || len(solutions) == 0 {
+		return
+	}
+	order := make([]int, len(solutions))
+	for x := range order {
+		order[x] = x
+	}
+	sort.SliceStable(order, func(i, j int) bool { return solutions[order[i]].Reward > solutions[order[j]].Reward })
+	pro := s.processor(0)
+	evaluate := func(program string) *Solution {
+		solution, hit := s.evaluateProgram(pro, program)
+		evaluations++
+		if hit {
+			hits++
+		}
+		return solution
+	}
+	for _, x := range order[:Min(s.Optimizer.Elites, len(order))] {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+		solutions[x] = s.Optimizer.Optimize(solutions[x], evaluate)
+	}
+	return
+}
@@ -0,0 +1,158 @@
+package goevolve
+
+import (
+	"math"
+	"sort"
+)
+
+// ParetoSelector is an NSGA-II style selector. It keeps whole non-dominated fronts
+// while they fit and fills the rest from the next front by crowding distance.
+// Solutions without Objectives are ranked on Reward alone.
+type ParetoSelector struct {
+	Keep int
+}
+
+func Pareto(keep int) *ParetoSelector {
+	return &ParetoSelector{keep}
+}
+
+func (sel ParetoSelector) Select(s *SolutionList) *SolutionList {
+	keep := make(SolutionList, 0, sel.Keep)
+	for _, front := range NonDominatedSort(*s) {
+		if len(keep)+len(front) <= sel.Keep {
+			keep = append(keep, front...)
+			continue
+		}
+		distance := CrowdingDistance(front)
+		index := make([]int, len(front))
+		for i := range index {
+			index[i] = i
+		}
+		sort.SliceStable(index, func(i, j int) bool { return distance[index[i]] > distance[index[j]] })
+		for _, i := range index[:sel.Keep-len(keep)] {
+			keep = append(keep, front[i])
+		}
+		break
+	}
+	return &keep
+}
+
+func objectivesOf(s *Solution) []float64 {
+	if len(s.Objectives) == 0 {
+		return []float64{s.Reward}
+	}
+	return s.Objectives
+}
+
+// Dominates reports whether a is at least as good as b on every objective and better on one.
+func Dominates(a, b *Solution) bool {
+	left, right := objectivesOf(a), objectivesOf(b)
+	better := false
+	for i := 0; i < len(left) && i < len(right); i++ {
+		if left[i] < right[i] {
+			return false
+		}
+		if left[i] > right[i] {
+			better = true
+		}
+	}
+	return better
+}
+
+// NonDominatedSort splits solutions into successive Pareto fronts, best first.
+func NonDominatedSort(solutions SolutionList) []SolutionList {
+	dominatedBy := make([]int, len(solutions))
+	dominates := make([][]int, len(solutions))
+	current := make([]int, 0)
+	for i := range solutions {
+		for j := range solutions {
+			if i == j {
+				continue
+			}
+			if Dominates(solutions[i], solutions[j]) {
+				dominates[i] = append(dominates[i], j)
+			} else if Dominates(solutions[j], solutions[i]) {
+				dominatedBy[i]++
+			}
+		}
+		if dominatedBy[i] == 0 {
+			current = append(current, i)
+		}
+	}
+	fronts := make([]SolutionList, 0)
+	for len(current) > 0 {
+		front := make(SolutionList, len(current))
+		next := make([]int, 0)
+		for x, i := range current {
+			front[x] = solutions[i]
+			for _, j := range dominates[i] {
+				dominatedBy[j]--
+				if dominatedBy[j] == 0 {
+					next = append(next, j)
+				}
+			}
+		}
+		fronts = append(fronts, front)
+		current = next
+	}
+	return fronts
+}
+
+// CrowdingDistance measures how isolated each member of a front is in objective space.
+// The extremes of every objective get an infinite distance.
+func CrowdingDistance(front SolutionList) []float64 {
+	distance := make([]float64, len(front))
+	if len(front) == 0 {
+		return distance
+	}
+	index := make([]int, len(front))
+	for m := range objectivesOf(front[0]) {
+		for i := range index {
+			index[i] = i
+		}
+		value := func(i int) float64 {
+			o := objectivesOf(front[index[i]])
+			if m < len(o) {
+				return o[m]
+			}
+			return 0
+		}
+		sort.SliceStable(index, func(i, j int) bool { return value(i) < value(j) })
+		low, high := value(0), value(len(index)-1)
+		distance[index[0]] = math.Inf(1)
+		distance[index[len(index)-1]] = math.Inf(1)
+		if high == low {
+			continue
+		}
+		for i := 1; i < len(index)-1; i++ {
+			distance[index[i]] += (value(i+1) - value(i-1)) / (high - low)
+		}
+	}
+	return distance
+}
+
+// ParetoFront returns the non-dominated members of solutions, one per program.
+func ParetoFront(solutions SolutionList) SolutionList {
+	unique := make(SolutionList, 0, len(solutions))
+	seen := make(map[string]bool)
+	for _, solution := range solutions {
+		if !seen[solution.Program] {
+			seen[solution.Program] = true
+			unique = append(unique, solution)
+		}
+	}
+	front := make(SolutionList, 0)
+	for _, candidate := range unique {
+		dominated := false
+		for _, other := range unique {
+			if Dominates(other, candidate) {
+				dominated = true
+				break
+			}
+		}
+		if !dominated {
+			front = append(front, candidate)
+		}
+	}
+	return front
+}
@@ -1,155 +1,430 @@
 package goevolve
 
 import (
-	"bytes"
-	"crypto/sha256"
-	"encoding/gob"
-	"fmt"
+	"context"
 	"github.com/tsavo/GoVirtual"
-	"io"
 	"log"
-	"os"
+	"sync"
 	"time"
 )
 
 type Population struct {
+	RNGSource
 	Id, RegisterLength   int
 	InstructionSet       *govirtual.InstructionSet
 	Breeder              *Breeder
 	Evaluator            *Evaluator
 	Selector             *Selector
 	TerminationCondition *govirtual.TerminationCondition
-	ControlChan          chan bool
 	PopulationReportChan chan *PopulationReport
 	Heap                 *govirtual.Memory
+	Workers              int
+	Store                SolutionStore
+	Optimizer            *ConstantOptimizer
+	MaxProgramLength     int
+	Parsimony            bool
+	Simplifier           *Simplifier
+	Replacement          ReplacementStrategy
+	Archive              *NoveltyArchive
+	Generation           int
+	LastReport           *PopulationReport
+	ParetoFront          SolutionList
+	programs             []string
+	processors           []*govirtual.Processor
+	cancel               context.CancelFunc
+	drain                chan bool
+	done                 chan bool
+	paused               chan bool
+	lock                 sync.Mutex
 }
 
-var SolutionCache map[string]*Solution
+// How long a stopping Population waits to hand over its final report.
+const FinalReportTimeout = 5 * time.Second
 
-func init() {
-	SolutionCache = make(map[string]*Solution)
-	go func() {
-		for {
-			time.Sleep(60 * time.Second)
-			WriteSolutionCache(EncodeSolutionCache())
-		}
-	}()
+// Solution is an evaluated program. Length is its line count when the Population
+// that scored it breaks ties by parsimony, and zero otherwise.
+type Solution struct {
+	Fitness
+	Program string
+	Length  int
+}
 
-	defer recover()
-	cache := ReadSolutionCache()
-	if cache != nil {
-		SolutionCache = *DecodeSolutionCache(cache)
+type SolutionList []*Solution
+
+func (sol *SolutionList) GetPrograms() []string {
+	x := make([]string, len(*sol))
+	for i, solution := range *sol {
+		x[i] = solution.Program
 	}
+	return x
+}
+
+type PopulationReport struct {
+	Id int
+	SolutionList
+	GenerationStats
+	ParetoFront SolutionList
 }
 
-func EncodeSolutionCache() (b *bytes.Buffer) {
-	b = new(bytes.Buffer)
-	e := gob.NewEncoder(b)
+func (s SolutionList) Len() int      { return len(s) }
+func (s SolutionList) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
 
-	// Encoding the map
-	err := e.Encode(&SolutionCache)
-	if err != nil {
-		panic(err)
+// Less ranks by Reward and breaks ties in favour of the shorter Length.
+func (s SolutionList) Less(i, j int) bool {
+	if s[i].Reward != s[j].Reward {
+		return s[i].Reward > s[j].Reward
 	}
-	return
+	return s[i].Length < s[j].Length
 }
 
-func DecodeSolutionCache(b *bytes.Buffer) *map[string]*Solution {
-	s := make(map[string]*Solution)
-	d := gob.NewDecoder(b)
+func NewPopulation(id int, sharedMemory *govirtual.Memory, rl int, is *govirtual.InstructionSet, term govirtual.TerminationCondition, gen Breeder, eval Evaluator, selector Selector) *Population {
+	return &Population{Id: id, RegisterLength: rl, InstructionSet: is, Breeder: &gen, Evaluator: &eval, Selector: &selector, TerminationCondition: &term, PopulationReportChan: make(chan *PopulationReport, 1), Heap: sharedMemory, Store: NewMemoryStore(DefaultStoreShards), Parsimony: true}
+}
 
-	// Decoding the serialized data
-	err := d.Decode(&s)
-	if err != nil {
-		return nil
+func (s *Population) Start(ctx context.Context) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.done != nil {
+		return
 	}
-	return &s
+	ctx, s.cancel = context.WithCancel(ctx)
+	s.drain = make(chan bool, 1)
+	s.done = make(chan bool)
+	go func(done chan bool) {
+		defer close(done)
+		s.Run(ctx)
+	}(s.done)
 }
 
-func WriteSolutionCache(b *bytes.Buffer) {
-	f, _ := os.OpenFile("SolutionCache.gob", os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0777)
-	f.Write(b.Bytes()) // Error handling elided for brevity.
-	f.Close()
+// Stop cancels the generation in progress, reports what was evaluated so far and waits for Run to return.
+func (s *Population) Stop() {
+	s.lock.Lock()
+	cancel := s.cancel
+	s.lock.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+	s.Wait()
 }
 
-func ReadSolutionCache() *bytes.Buffer {
-	buf := bytes.NewBuffer(nil)
-	f, _ := os.Open("SolutionCache.gob") // Error handling elided for brevity.
-	written, err := io.Copy(buf, f)      // Error handling elided for brevity.
-	f.Close()
-	if err == nil && written > 0 {
-		return buf
+// Drain lets the generation in progress finish, reports it and waits for Run to return.
+func (s *Population) Drain() {
+	s.lock.Lock()
+	drain := s.drain
+	s.lock.Unlock()
+	if drain != nil {
+		select {
+		case drain <- true:
+		default:
+		}
 	}
-	return nil
+	s.Wait()
 }
 
-type Solution struct {
-	Reward  int
-	Program string
+func (s *Population) Wait() {
+	s.lock.Lock()
+	done := s.done
+	s.lock.Unlock()
+	if done != nil {
+		<-done
+	}
 }
 
-type SolutionList []*Solution
+func (s *Population) Report() *PopulationReport {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.LastReport
+}
 
-func (sol *SolutionList) GetPrograms() []string {
-	x := make([]string, len(*sol))
-	for i, solution := range *sol {
-		x[i] = solution.Program
+// Pause holds the population at the end of its current generation until Resume is called.
+func (s *Population) Pause() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.paused == nil {
+		s.paused = make(chan bool)
 	}
-	return x
 }
 
-type PopulationReport struct {
-	Id int
-	SolutionList
+func (s *Population) Resume() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.paused != nil {
+		close(s.paused)
+		s.paused = nil
+	}
 }
 
-func (s SolutionList) Len() int           { return len(s) }
-func (s SolutionList) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
-func (s SolutionList) Less(i, j int) bool { return s[i].Reward > s[j].Reward }
+func (s *Population) Paused() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.paused != nil
+}
 
-func NewPopulation(id int, sharedMemory *govirtual.Memory, rl int, is *govirtual.InstructionSet, term govirtual.TerminationCondition, gen Breeder, eval Evaluator, selector Selector) *Population {
-	return &Population{id, rl, is, &gen, &eval, &selector, &term, make(chan bool, 1), make(chan *PopulationReport, 1), sharedMemory}
+// hold blocks while the population is paused. It returns false if the population
+// was drained or cancelled in the meantime.
+func (s *Population) hold(ctx context.Context) bool {
+	s.lock.Lock()
+	paused := s.paused
+	s.lock.Unlock()
+	if paused == nil {
+		return true
+	}
+	select {
+	case <-paused:
+		return true
+	case <-s.drain:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (s *Population) publish(report *PopulationReport, final bool) {
+	s.lock.Lock()
+	s.LastReport = report
+	s.lock.Unlock()
+	if !final {
+		select {
+		case s.PopulationReportChan <- report:
+		default:
+		}
+		return
+	}
+	select {
+	case s.PopulationReportChan <- report:
+	case <-time.After(FinalReportTimeout):
+		log.Printf("#%d: dropped final report\n", s.Id)
+	}
 }
 
-func (s *Population) Run() {
-	programs := (*s.Breeder).Breed((*s.Breeder).Breed(nil))
-	processors := make([]*govirtual.Processor, 0)
+func (s *Population) Run(ctx context.Context) {
+	s.lock.Lock()
+	programs := s.programs
+	generation := s.Generation
+	s.lock.Unlock()
+	if s.Archive != nil {
+		useArchive(*s.Selector, s.Archive)
+	}
+	if s.Heap != nil {
+		constrain(*s.Breeder, s.RegisterLength, len(*s.Heap))
+	} else {
+		constrain(*s.Breeder, s.RegisterLength, 0)
+	}
+	if programs == nil {
+		// The first generation is bred on streams of its own.
+		r := s.streams(generation - 1)
+		programs = s.limit(r, (*s.Breeder).Breed((*s.Breeder).Breed(nil)))
+		s.lock.Lock()
+		s.programs = programs
+		s.lock.Unlock()
+	}
+	var current SolutionList
 	for {
-		solutions := make(SolutionList, len(programs))
-		for len(processors) < len(solutions) {
-			c := govirtual.NewProcessor(s.Id, s.RegisterLength, s.InstructionSet, s.Heap, s.TerminationCondition)
-			processors = append(processors, c)
+		r := s.streams(generation)
+		started := time.Now()
+		solutions, hits, complete := s.evaluate(ctx, programs)
+		evaluations := len(solutions)
+		if complete {
+			n, h := s.optimize(ctx, solutions)
+			evaluations, hits = evaluations+n, hits+h
+			n, h = s.simplify(solutions)
+			evaluations, hits = evaluations+n, hits+h
+		}
+		if current == nil {
+			current = solutions
+		} else {
+			current = s.replacement().Replace(current, solutions)
+		}
+		// A cancelled generation leaves the programs and generation it started
+		// from in place, so it is bred and evaluated again when the population resumes.
+		if complete {
+			s.lock.Lock()
+			s.programs = current.GetPrograms()
+			s.Generation = generation
+			s.lock.Unlock()
 		}
-		if len(processors) > len(solutions) {
-			processors = processors[:len(solutions)]
+		stats := NewGenerationStats(generation, time.Since(started), current, evaluations, hits)
+		// Selectors may sort current in place, so the report gets its own copy.
+		report := &PopulationReport{s.Id, append(SolutionList{}, current...), stats, s.updateParetoFront(solutions)}
+		if !complete {
+			s.publish(report, true)
+			return
+		}
+		select {
+		case <-s.drain:
+			s.publish(report, true)
+			return
+		case <-ctx.Done():
+			s.publish(report, true)
+			return
+		default:
 		}
+		s.publish(report, false)
+		adapt(*s.Breeder, report)
+		selected := (*s.Selector).Select(&current)
+		if s.Archive != nil {
+			s.Archive.Update(solutions)
+		}
+		offspring := (*s.Breeder).Breed(selected.GetPrograms())
+		if n := s.replacement().Offspring(len(current)); n > 0 && n < len(offspring) {
+			offspring = sample(r, offspring, n)
+		}
+		programs = s.limit(r, offspring)
+		generation++
+		if !s.hold(ctx) {
+			s.publish(report, true)
+			return
+		}
+	}
+}
 
-		for x, pro := range processors {
-			select {
-			case <-s.ControlChan:
-				return
-			default:
-			}
-			log.Printf("#%d: %d\n", s.Id, x)
-			sha := fmt.Sprintf("%x", sha256.Sum256([]byte(programs[x])))
-			sol, notNeeded := SolutionCache[sha]
-			if notNeeded {
-				solutions[x] = sol
-			} else {
-				pro.Reset()
-				pro.CompileAndLoad(programs[x])
-				pro.Run()
-				solutions[x] = &Solution{(*s.Evaluator).Evaluate(pro), programs[x]}
-				potential, present := SolutionCache[sha]
-				if !present || potential.Reward > solutions[x].Reward {
-					SolutionCache[sha] = solutions[x]
-				}
+// streams hands the breeder, selector and optimizer their random streams for a
+// generation and returns the population's own. Every stream depends only on the
+// seed and the generation, so a restored population carries on exactly as the
+// one it was checkpointed from.
+func (s *Population) streams(generation int) *SafeRNG {
+	s.lock.Lock()
+	r := s.random().Derive(int64(generation))
+	s.lock.Unlock()
+	inheritRNG(*s.Breeder, r.Derive(1))
+	inheritRNG(*s.Selector, r.Derive(2))
+	if s.Optimizer != nil {
+		s.Optimizer.InheritRNG(r.Derive(3))
+	}
+	return r
+}
+
+func (s *Population) replacement() ReplacementStrategy {
+	if s.Replacement == nil {
+		return Generational{}
+	}
+	return s.Replacement
+}
+
+// updateParetoFront merges a generation into the non-dominated solutions seen so
+// far. It is only maintained once an Evaluator reports Objectives.
+func (s *Population) updateParetoFront(solutions SolutionList) SolutionList {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, solution := range solutions {
+		if len(solution.Objectives) > 0 {
+			s.ParetoFront = ParetoFront(append(append(SolutionList{}, s.ParetoFront...), solutions...))
+			break
+		}
+	}
+	return s.ParetoFront
+}
+
+func (s *Population) workers() int {
+	if s.Workers < 1 {
+		return 1
+	}
+	return s.Workers
+}
+
+// processor returns the Processor owned by the given worker. A single worker runs
+// directly against the shared Heap; with more than one, every worker gets a private
+// heap that is refreshed from the shared Heap before each evaluation.
+func (s *Population) processor(worker int) *govirtual.Processor {
+	for len(s.processors) <= worker {
+		heap := s.Heap
+		if s.workers() > 1 {
+			size := 0
+			if s.Heap != nil {
+				size = len(*s.Heap)
 			}
+			private := make(govirtual.Memory, size)
+			heap = &private
 		}
+		s.processors = append(s.processors, govirtual.NewProcessor(s.Id, s.RegisterLength, s.InstructionSet, heap, s.TerminationCondition))
+	}
+	return s.processors[worker]
+}
+
+// evaluate scores programs on the worker pool. The returned SolutionList is in the
+// same order as programs; if ctx is cancelled part way through only the programs
+// that were evaluated are returned and complete is false. hits counts the programs
+// answered by the Store. With more than one worker the Evaluator is called
+// concurrently and must be safe for concurrent use.
+func (s *Population) evaluate(ctx context.Context, programs []string) (solutions SolutionList, hits int, complete bool) {
+	solutions = make(SolutionList, len(programs))
+	cached := make([]bool, len(programs))
+	jobs := make(chan int)
+	var wait sync.WaitGroup
+	for w := 0; w < Min(s.workers(), len(programs)); w++ {
+		wait.Add(1)
+		go func(pro *govirtual.Processor) {
+			defer wait.Done()
+			for x := range jobs {
+				log.Printf("#%d: %d\n", s.Id, x)
+				solutions[x], cached[x] = s.evaluateProgram(pro, programs[x])
+			}
+		}(s.processor(w))
+	}
+	complete = true
+feed:
+	for x := range programs {
 		select {
-		case s.PopulationReportChan <- &PopulationReport{s.Id, solutions}:
-		default:
+		case <-ctx.Done():
+			complete = false
+			break feed
+		case jobs <- x:
 		}
-		programs = (*s.Breeder).Breed((*s.Selector).Select(&solutions).GetPrograms())
 	}
+	close(jobs)
+	wait.Wait()
+	for _, hit := range cached {
+		if hit {
+			hits++
+		}
+	}
+	if !complete {
+		evaluated := make(SolutionList, 0, len(solutions))
+		for _, solution := range solutions {
+			if solution != nil {
+				evaluated = append(evaluated, solution)
+			}
+		}
+		solutions = evaluated
+	}
+	return
+}
+
+func (s *Population) evaluateProgram(pro *govirtual.Processor, program string) (*Solution, bool) {
+	key := SolutionKey(program)
+	if s.Store != nil {
+		if sol, notNeeded := s.Store.Get(key); notNeeded {
+			return s.measure(sol), true
+		}
+	}
+	if s.Heap != nil && pro.Heap != s.Heap {
+		copy(*pro.Heap, *s.Heap)
+	}
+	f, err := s.run(pro, program)
+	sol := s.measure(&Solution{Fitness: f, Program: program})
+	if err != nil {
+		log.Printf("#%d: %v\n", s.Id, err)
+	} else if s.Store != nil {
+		s.Store.Put(key, sol)
+	}
+	return sol, false
+}
+
+// measure sets the solution's Length when Parsimony is on and clears it when it
+// is off. Stored solutions may be shared, so a solution that needs changing is copied.
+func (s *Population) measure(sol *Solution) *Solution {
+	length := 0
+	if s.Parsimony {
+		length = ProgramLength(sol.Program)
+	}
+	if sol.Length == length {
+		return sol
+	}
+	out := *sol
+	out.Length = length
+	return &out
+}
+
+func (s *Population) run(pro *govirtual.Processor, program string) (Fitness, error) {
+	return TryRunProgram(*s.Evaluator, pro, program)
 }
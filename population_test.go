@@ -0,0 +1,144 @@
+package goevolve
+
+import (
+	"context"
+	"github.com/tsavo/GoVirtual"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var testPrograms = []string{
+	":start\nset 1,2\nadd 1,1\njump :start\n",
+	":start\nset 0,7\n:loop\ndecrement 0\njumpIfNotZero :loop\n",
+	"set 2,3\nset 3,4\nadd 2,3\nreturn\n",
+	":start\npush 1\npop 2\nsubtract 2,1\n",
+	"set 1,100\n:again\nsubtract 1,7\njumpIfGreaterThan :again\nnoop\n",
+}
+
+// lengthEvaluator scores the program text itself, so tests need no instruction set.
+type lengthEvaluator struct{}
+
+func (lengthEvaluator) Evaluate(*govirtual.Processor) Fitness {
+	return Fitness{}
+}
+
+func (lengthEvaluator) EvaluateProgram(p *govirtual.Processor, program string) Fitness {
+	return Fitness{Reward: float64(len(program) % 13)}
+}
+
+func newTestPopulation(t *testing.T, seed int64) *Population {
+	heap := make(govirtual.Memory, 4)
+	var term govirtual.TerminationCondition
+	population := NewPopulation(0, &heap, 4, govirtual.NewInstructionSet(), term, NewCrossoverBreeder(20), lengthEvaluator{}, Tournament(10))
+	population.PopulationReportChan = make(chan *PopulationReport, 100)
+	if err := population.Restore(PopulationCheckpoint{Seed: seed, Programs: testPrograms}); err != nil {
+		t.Fatal(err)
+	}
+	return population
+}
+
+// evolve runs a population seeded with seed and returns the programs of its
+// first generations.
+func evolve(t *testing.T, seed int64, generations int) [][]string {
+	return collect(t, newTestPopulation(t, seed), generations)
+}
+
+// collect runs population and returns the programs it reports for each
+// generation before the given one.
+func collect(t *testing.T, population *Population, generations int) [][]string {
+	seen := population.Generation
+	population.Start(context.Background())
+	defer population.Stop()
+	out := make([][]string, generations)
+	for seen < generations {
+		select {
+		case report := <-population.PopulationReportChan:
+			if report.Generation < generations && out[report.Generation] == nil {
+				out[report.Generation] = report.GetPrograms()
+				seen++
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatal("timed out waiting for a report")
+		}
+	}
+	return out
+}
+
+func TestSeededPopulationIsReproducible(t *testing.T) {
+	first := evolve(t, 42, 5)
+	second := evolve(t, 42, 5)
+	if !reflect.DeepEqual(first, second) {
+		t.Fatalf("two runs with the same seed diverged:\n%q\n%q", first, second)
+	}
+}
+
+func TestRestoredPopulationContinuesItsRun(t *testing.T) {
+	uninterrupted := evolve(t, 42, 6)
+	population := newTestPopulation(t, 42)
+	if err := population.Restore(PopulationCheckpoint{Generation: 3, Seed: 42, Programs: uninterrupted[3]}); err != nil {
+		t.Fatal(err)
+	}
+	resumed := collect(t, population, 6)
+	if !reflect.DeepEqual(uninterrupted[3:], resumed[3:]) {
+		t.Fatalf("the restored population diverged:\n%q\n%q", uninterrupted[3:], resumed[3:])
+	}
+}
+
+// gateEvaluator blocks its nth evaluation until release is closed, so a test can
+// stop a population part way through a generation.
+type gateEvaluator struct {
+	lengthEvaluator
+	n       int
+	calls   *int32
+	reached chan bool
+	release chan bool
+}
+
+func (e gateEvaluator) EvaluateProgram(p *govirtual.Processor, program string) Fitness {
+	if int(atomic.AddInt32(e.calls, 1)) == e.n {
+		close(e.reached)
+		<-e.release
+	}
+	return e.lengthEvaluator.EvaluateProgram(p, program)
+}
+
+func TestStoppedSteadyStateKeepsItsSize(t *testing.T) {
+	population := newTestPopulation(t, 42)
+	population.Replacement = SteadyState{Count: 2}
+	population.Store = nil
+	gate := gateEvaluator{n: len(testPrograms) + 1, calls: new(int32), reached: make(chan bool), release: make(chan bool)}
+	*population.Evaluator = gate
+	ctx, cancel := context.WithCancel(context.Background())
+	population.Start(ctx)
+	select {
+	case <-gate.reached:
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for the second generation")
+	}
+	cancel()
+	close(gate.release)
+	population.Wait()
+	checkpoint := population.Checkpoint()
+	if len(checkpoint.Programs) != len(testPrograms) {
+		t.Fatalf("stopped with %d programs, want %d", len(checkpoint.Programs), len(testPrograms))
+	}
+	resumed := newTestPopulation(t, 42)
+	resumed.Replacement = SteadyState{Count: 2}
+	if err := resumed.Restore(checkpoint); err != nil {
+		t.Fatal(err)
+	}
+	resumed.Start(context.Background())
+	defer resumed.Stop()
+	for x := 0; x < 3; x++ {
+		select {
+		case report := <-resumed.PopulationReportChan:
+			if len(report.SolutionList) != len(testPrograms) {
+				t.Fatalf("resumed generation %d has %d solutions, want %d", report.Generation, len(report.SolutionList), len(testPrograms))
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatal("timed out waiting for a report")
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package goevolve
+
+import (
+	"math"
+	"sort"
+)
+
+// FitnessScaling turns rewards, which may be negative, zero or huge, into
+// non-negative selection weights.
+type FitnessScaling interface {
+	Scale(solutions SolutionList) []float64
+}
+
+// WindowScaling weighs each solution by how far it is above the worst one.
+type WindowScaling struct{}
+
+func (WindowScaling) Scale(solutions SolutionList) []float64 {
+	worst := math.Inf(1)
+	for _, solution := range solutions {
+		worst = math.Min(worst, solution.Reward)
+	}
+	weights := make([]float64, len(solutions))
+	for x, solution := range solutions {
+		weights[x] = solution.Reward - worst
+	}
+	return weights
+}
+
+// SigmaScaling weighs each solution by 1 + (reward - mean) / (C * standard
+// deviation), floored at zero.
+type SigmaScaling struct {
+	C float64
+}
+
+func (sigma SigmaScaling) Scale(solutions SolutionList) []float64 {
+	mean, deviation := 0.0, 0.0
+	for _, solution := range solutions {
+		mean += solution.Reward / float64(len(solutions))
+	}
+	for _, solution := range solutions {
+		deviation += (solution.Reward - mean) * (solution.Reward - mean) / float64(len(solutions))
+	}
+	deviation = math.Sqrt(deviation)
+	weights := make([]float64, len(solutions))
+	for x, solution := range solutions {
+		weights[x] = 1
+		if deviation > 0 && sigma.C > 0 {
+			weights[x] = math.Max(0, 1+(solution.Reward-mean)/(sigma.C*deviation))
+		}
+	}
+	return weights
+}
+
+// RankScaling is linear ranking: the worst solution weighs 2 - Pressure and the
+// best Pressure, with Pressure between 1 and 2.
+type RankScaling struct {
+	Pressure float64
+}
+
+func (rank RankScaling) Scale(solutions SolutionList) []float64 {
+	order := make([]int, len(solutions))
+	for x := range order {
+		order[x] = x
+	}
+	sort.SliceStable(order, func(i, j int) bool { return solutions[order[i]].Reward < solutions[order[j]].Reward })
+	pressure := math.Max(1, math.Min(2, rank.Pressure))
+	weights := make([]float64, len(solutions))
+	for r, x := range order {
+		weights[x] = 1
+		if len(solutions) > 1 {
+			weights[x] = 2 - pressure + 2*(pressure-1)*float64(r)/float64(len(solutions)-1)
+		}
+	}
+	return weights
+}
+
+// normalizedWeights scales weights so they sum to 1 without overflowing. When no
+// solution has any weight every solution gets the same.
+func normalizedWeights(scaling FitnessScaling, solutions SolutionList) []float64 {
+	if scaling == nil {
+		scaling = WindowScaling{}
+	}
+	weights := scaling.Scale(solutions)
+	largest := 0.0
+	for x, w := range weights {
+		if math.IsNaN(w) || w < 0 {
+			weights[x] = 0
+		} else if math.IsInf(w, 1) {
+			weights[x] = math.MaxFloat64
+		}
+		largest = math.Max(largest, weights[x])
+	}
+	total := 0.0
+	for x := range weights {
+		if largest > 0 {
+			weights[x] /= largest
+		} else {
+			weights[x] = 1
+		}
+		total += weights[x]
+	}
+	for x := range weights {
+		weights[x] /= total
+	}
+	return weights
+}
+
+// RouletteSelector spins a roulette wheel Keep times.
+type RouletteSelector struct {
+	Keep    int
+	Scaling FitnessScaling
+	RNGSource
+}
+
+func Roulette(keep int, scaling FitnessScaling) *RouletteSelector {
+	return &RouletteSelector{Keep: keep, Scaling: scaling}
+}
+
+func (sel RouletteSelector) Select(s *SolutionList) *SolutionList {
+	if len(*s) == 0 {
+		return &SolutionList{}
+	}
+	rng := sel.random()
+	pointers := make([]float64, sel.Keep)
+	for i := range pointers {
+		pointers[i] = rng.Float64()
+	}
+	sort.Float64s(pointers)
+	return RWS(s, normalizedWeights(sel.Scaling, *s), pointers)
+}
+
+// StochasticUniversalSelector spins the wheel once with Keep evenly spaced pointers.
+type StochasticUniversalSelector struct {
+	Keep    int
+	Scaling FitnessScaling
+	RNGSource
+}
+
+func NewStochasticUniversalSelector(keep int) *StochasticUniversalSelector {
+	return &StochasticUniversalSelector{Keep: keep, Scaling: WindowScaling{}}
+}
+
+// LinearRanking is stochastic universal sampling over RankScaling weights.
+func LinearRanking(keep int, pressure float64) *StochasticUniversalSelector {
+	return &StochasticUniversalSelector{Keep: keep, Scaling: RankScaling{pressure}}
+}
+
+func (sel StochasticUniversalSelector) Select(s *SolutionList) *SolutionList {
+	if len(*s) == 0 || sel.Keep <= 0 {
+		return &SolutionList{}
+	}
+	rng := sel.random()
+	p := 1 / float64(sel.Keep)
+	start := rng.Float64() * p
+	pointers := make([]float64, sel.Keep)
+	for i := range pointers {
+		pointers[i] = start + float64(i)*p
+	}
+	return RWS(s, normalizedWeights(sel.Scaling, *s), pointers)
+}
+
+// RWS walks the cumulative weights of solutions and keeps the solution under
+// each pointer. pointers must be ascending and within the total weight.
+func RWS(solutions *SolutionList, weights, pointers []float64) *SolutionList {
+	keep := make(SolutionList, 0, len(pointers))
+	i, cumulative := 0, weights[0]
+	for _, p := range pointers {
+		for cumulative < p && i < len(*solutions)-1 {
+			i++
+			cumulative += weights[i]
+		}
+		keep = append(keep, (*solutions)[i])
+	}
+	return &keep
+}
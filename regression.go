@@ -0,0 +1,173 @@
+package goevolve
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/tsavo/GoVirtual"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ProgramEvaluator is implemented by evaluators that run the program themselves,
+// for example once per test case. Populations call EvaluateProgram instead of
+// running the program and calling Evaluate.
+type ProgramEvaluator interface {
+	Evaluator
+	EvaluateProgram(p *govirtual.Processor, program string) Fitness
+}
+
+type TestCase struct {
+	Inputs   []int `json:"inputs"`
+	Expected []int `json:"expected"`
+}
+
+type ErrorMetric int
+
+const (
+	AbsoluteError ErrorMetric = iota
+	SquaredError
+	ExactMatch
+)
+
+// CaseEvaluator runs a program once per TestCase. Inputs are written to
+// InputAddresses and outputs read from OutputAddresses, on the heap or, when
+// Registers is set, in the registers. The error metrics score the negated total
+// error; ExactMatch scores the number of cases whose outputs all match. The
+// outputs of every case make up the program's Behavior. Every case starts from
+// the memory the first one started from, whatever earlier cases left behind.
+type CaseEvaluator struct {
+	Cases                           []TestCase
+	InputAddresses, OutputAddresses []int
+	Registers                       bool
+	Metric                          ErrorMetric
+}
+
+func NewCaseEvaluator(cases []TestCase, inputs, outputs []int, metric ErrorMetric) *CaseEvaluator {
+	return &CaseEvaluator{Cases: cases, InputAddresses: inputs, OutputAddresses: outputs, Metric: metric}
+}
+
+func (eval *CaseEvaluator) memory(p *govirtual.Processor) *govirtual.Memory {
+	if eval.Registers {
+		return &p.Registers
+	}
+	return p.Heap
+}
+
+func (eval *CaseEvaluator) EvaluateProgram(p *govirtual.Processor, program string) Fitness {
+	f := Fitness{}
+	var initial govirtual.Memory
+	for x, c := range eval.Cases {
+		p.Reset()
+		p.CompileAndLoad(program)
+		m := eval.memory(p)
+		if x == 0 {
+			initial = append(govirtual.Memory{}, *m...)
+		} else {
+			copy(*m, initial)
+		}
+		for i, address := range eval.InputAddresses {
+			if i < len(c.Inputs) {
+				m.Set(address, c.Inputs[i])
+			}
+		}
+		p.Run()
+		m = eval.memory(p)
+		f.Reward += eval.score(m, c)
+		for _, address := range eval.OutputAddresses {
+			f.Behavior = append(f.Behavior, float64(m.Get(address)))
+		}
+	}
+	return f
+}
+
+// Evaluate scores a Processor that has already run with the first case's inputs loaded.
+func (eval *CaseEvaluator) Evaluate(p *govirtual.Processor) Fitness {
+	if len(eval.Cases) == 0 {
+		return Fitness{}
+	}
+	return Fitness{Reward: eval.score(eval.memory(p), eval.Cases[0])}
+}
+
+func (eval *CaseEvaluator) score(m *govirtual.Memory, c TestCase) float64 {
+	score, matched := 0.0, true
+	for i, address := range eval.OutputAddresses {
+		if i >= len(c.Expected) {
+			break
+		}
+		diff := float64(m.Get(address) - c.Expected[i])
+		if diff != 0 {
+			matched = false
+		}
+		switch eval.Metric {
+		case AbsoluteError:
+			score -= math.Abs(diff)
+		case SquaredError:
+			score -= diff * diff
+		}
+	}
+	if eval.Metric == ExactMatch && matched {
+		return 1
+	}
+	return score
+}
+
+// ReadCasesCSV reads one case per record: the first inputs fields are the inputs
+// and the rest the expected outputs. A non-numeric first record is skipped as a header.
+func ReadCasesCSV(r io.Reader, inputs int) ([]TestCase, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	cases := make([]TestCase, 0, len(records))
+	for line, record := range records {
+		if len(record) < inputs {
+			return nil, fmt.Errorf("goevolve: case %d has %d fields, want at least %d", line+1, len(record), inputs)
+		}
+		values := make([]int, len(record))
+		for i, field := range record {
+			if values[i], err = strconv.Atoi(strings.TrimSpace(field)); err != nil {
+				break
+			}
+		}
+		if err != nil {
+			if line == 0 {
+				err = nil
+				continue
+			}
+			return nil, fmt.Errorf("goevolve: case %d: %v", line+1, err)
+		}
+		cases = append(cases, TestCase{values[:inputs], values[inputs:]})
+	}
+	return cases, nil
+}
+
+// ReadCasesJSON reads a JSON array of {"inputs": [...], "expected": [...]} objects.
+func ReadCasesJSON(r io.Reader) ([]TestCase, error) {
+	var cases []TestCase
+	if err := json.NewDecoder(r).Decode(&cases); err != nil {
+		return nil, err
+	}
+	return cases, nil
+}
+
+func LoadCasesCSV(path string, inputs int) ([]TestCase, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadCasesCSV(f, inputs)
+}
+
+func LoadCasesJSON(path string) ([]TestCase, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadCasesJSON(f)
+}
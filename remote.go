@@ -0,0 +1,310 @@
+package goevolve
+
+import (
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"time"
+)
+
+// Islands can run in other processes. A Worker joins an IslandEvolver serving on
+// a TCP address and runs its Populations there as remote islands: reports flow
+// to the evolver, which sends migrants and pause, resume, stop and drain
+// requests back. Messages are a gob stream in both directions.
+
+// ProtocolVersion must match between a Worker and the IslandEvolver it joins.
+const ProtocolVersion = 1
+
+// How long a peer may take to accept a message before the connection is dropped.
+const WriteTimeout = 30 * time.Second
+
+type MessageKind int
+
+const (
+	JoinMessage     MessageKind = iota // worker: run one more island
+	WelcomeMessage                     // evolver: the island's id and seed
+	ReportMessage                      // worker: a generation's report
+	DoneMessage                        // worker: the island has stopped
+	MigrantsMessage                    // evolver: programs for the island's inbox
+	PauseMessage
+	ResumeMessage
+	StopMessage
+	DrainMessage
+)
+
+type Message struct {
+	Kind     MessageKind
+	Version  int
+	Island   int
+	Seed     int64
+	Report   *PopulationReport
+	Programs []string
+}
+
+// peer is one end of a connection. send is safe for concurrent use; receive is
+// called from a single goroutine.
+type peer struct {
+	conn net.Conn
+	enc  *gob.Encoder
+	dec  *gob.Decoder
+	done chan bool
+	once sync.Once
+	lock sync.Mutex
+}
+
+func newPeer(conn net.Conn) *peer {
+	return &peer{conn: conn, enc: gob.NewEncoder(conn), dec: gob.NewDecoder(conn), done: make(chan bool)}
+}
+
+func (p *peer) send(m Message) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.conn.SetWriteDeadline(time.Now().Add(WriteTimeout))
+	return p.enc.Encode(&m)
+}
+
+func (p *peer) receive() (m Message, err error) {
+	err = p.dec.Decode(&m)
+	return
+}
+
+func (p *peer) close() {
+	p.once.Do(func() {
+		close(p.done)
+		p.conn.Close()
+	})
+}
+
+// ListenAndServe accepts Workers on addr. See Serve.
+func (self *IslandEvolver) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return self.Serve(l)
+}
+
+// Serve accepts Workers on l until it is closed. Every Population a Worker runs
+// becomes a remote island that starts as soon as it joins and is removed when
+// its Worker disconnects. Remote islands are not included in checkpoints.
+func (self *IslandEvolver) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go self.serveWorker(newPeer(conn))
+	}
+}
+
+func (self *IslandEvolver) serveWorker(p *peer) {
+	defer p.close()
+	islands := make(map[int]*Island)
+	defer func() {
+		for _, island := range islands {
+			self.disconnect(island)
+		}
+	}()
+	for {
+		m, err := p.receive()
+		if err != nil {
+			if err != io.EOF {
+				log.Printf("Worker %s: %v", p.conn.RemoteAddr(), err)
+			}
+			return
+		}
+		switch m.Kind {
+		case JoinMessage:
+			if m.Version != ProtocolVersion {
+				log.Printf("Worker %s: unsupported protocol version %d", p.conn.RemoteAddr(), m.Version)
+				return
+			}
+			island, seed := self.addRemoteIsland(p)
+			islands[island.Id] = island
+			if err := p.send(Message{Kind: WelcomeMessage, Island: island.Id, Seed: seed}); err != nil {
+				return
+			}
+		case ReportMessage:
+			if m.Report == nil || islands[m.Report.Id] == nil {
+				continue
+			}
+			select {
+			case self.PopulationReportChan <- m.Report:
+			case <-time.After(FinalReportTimeout):
+				log.Printf("#%d: dropped report\n", m.Report.Id)
+			}
+		case DoneMessage:
+			if island := islands[m.Island]; island != nil {
+				island.finish()
+			}
+		}
+	}
+}
+
+// addRemoteIsland registers an island run by p and forwards its migrants to p.
+func (self *IslandEvolver) addRemoteIsland(p *peer) (*Island, int64) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	inbox := make(InfluxBreeder, 100)
+	self.inboxes[self.lastId] = inbox
+	island := &Island{Id: self.lastId, Remote: p.conn.RemoteAddr().String(), evolver: self, remote: p, done: make(chan bool)}
+	self.islands = append(self.islands, island)
+	self.lastId++
+	go func() {
+		for {
+			select {
+			case programs := <-inbox:
+				if err := p.send(Message{Kind: MigrantsMessage, Island: island.Id, Programs: programs}); err != nil {
+					p.close()
+					return
+				}
+			case <-p.done:
+				return
+			}
+		}
+	}()
+	return island, self.random().Derive(int64(island.Id)).CurrentSeed()
+}
+
+// disconnect removes a remote island whose Worker has gone away.
+func (self *IslandEvolver) disconnect(island *Island) {
+	self.lock.Lock()
+	for x, i := range self.islands {
+		if i == island {
+			self.islands = append(self.islands[:x:x], self.islands[x+1:]...)
+			break
+		}
+	}
+	delete(self.inboxes, island.Id)
+	delete(self.reports, island.Id)
+	self.lock.Unlock()
+	island.finish()
+}
+
+// Worker runs Populations in this process as islands of the IslandEvolver
+// serving at Addr. Each Population keeps its own breeder, evaluator and selector;
+// the evolver assigns its Id and, unless one is set already, its RNG.
+type Worker struct {
+	Addr        string
+	Populations []*Population
+}
+
+func NewWorker(addr string, populations ...*Population) *Worker {
+	return &Worker{addr, populations}
+}
+
+// Run joins the evolver and runs every Population until ctx is done, the evolver
+// stops them or the connection is lost. It returns once they have all finished.
+func (w *Worker) Run(ctx context.Context) error {
+	if len(w.Populations) == 0 {
+		return errors.New("goevolve: worker has no populations")
+	}
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", w.Addr)
+	if err != nil {
+		return err
+	}
+	p := newPeer(conn)
+	defer p.close()
+	islands := make(map[int]*Population)
+	inboxes := make(map[int]InfluxBreeder)
+	reports := make(map[int]chan *PopulationReport)
+	for _, population := range w.Populations {
+		if err := p.send(Message{Kind: JoinMessage, Version: ProtocolVersion}); err != nil {
+			return err
+		}
+		m, err := p.receive()
+		if err != nil {
+			return err
+		}
+		if m.Kind != WelcomeMessage {
+			return fmt.Errorf("goevolve: unexpected message %d while joining", m.Kind)
+		}
+		population.Id = m.Island
+		population.InheritRNG(NewRNG(m.Seed))
+		inboxes[m.Island] = make(InfluxBreeder, 100)
+		*population.Breeder = Breeders(*population.Breeder, inboxes[m.Island])
+		reports[m.Island] = make(chan *PopulationReport, 100)
+		population.PopulationReportChan = reports[m.Island]
+		islands[m.Island] = population
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var wait sync.WaitGroup
+	for id, population := range islands {
+		population.Start(ctx)
+		wait.Add(1)
+		go func(id int, population *Population) {
+			defer wait.Done()
+			w.forward(p, id, population, reports[id])
+		}(id, population)
+	}
+	finished := make(chan bool)
+	go func() {
+		wait.Wait()
+		close(finished)
+		p.close()
+	}()
+	for {
+		m, err := p.receive()
+		if err != nil {
+			select {
+			case <-finished:
+				return nil
+			default:
+			}
+			cancel()
+			<-finished
+			return err
+		}
+		population := islands[m.Island]
+		if population == nil {
+			continue
+		}
+		switch m.Kind {
+		case MigrantsMessage:
+			select {
+			case inboxes[m.Island] <- m.Programs:
+			default:
+			}
+		case PauseMessage:
+			population.Pause()
+		case ResumeMessage:
+			population.Resume()
+		case StopMessage:
+			go population.Stop()
+		case DrainMessage:
+			go population.Drain()
+		}
+	}
+}
+
+// forward sends population's reports to the evolver until it stops, then sends
+// any reports still queued followed by a DoneMessage.
+func (w *Worker) forward(p *peer, id int, population *Population, reports chan *PopulationReport) {
+	stopped := make(chan bool)
+	go func() {
+		population.Wait()
+		close(stopped)
+	}()
+	for {
+		select {
+		case report := <-reports:
+			p.send(Message{Kind: ReportMessage, Island: id, Report: report})
+		case <-stopped:
+			for {
+				select {
+				case report := <-reports:
+					p.send(Message{Kind: ReportMessage, Island: id, Report: report})
+				default:
+					p.send(Message{Kind: DoneMessage, Island: id})
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package goevolve
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestWorkerRunsRemoteIslands(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	evolver := NewIslandEvolver()
+	champions := make(ChanSink, 100)
+	evolver.AddSink(champions)
+	evolver.Start(context.Background())
+	go evolver.Serve(l)
+
+	worker := NewWorker(l.Addr().String(), newTestPopulation(t, 1), newTestPopulation(t, 2))
+	done := make(chan error, 1)
+	go func() {
+		done <- worker.Run(context.Background())
+	}()
+
+	select {
+	case champion := <-champions:
+		if champion.Island != 0 && champion.Island != 1 {
+			t.Errorf("champion came from unknown island #%d", champion.Island)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for a champion")
+	}
+	islands := evolver.Islands()
+	if len(islands) != 2 {
+		t.Fatalf("evolver has %d islands, want 2", len(islands))
+	}
+	for _, island := range islands {
+		if island.Remote == "" || island.Population != nil {
+			t.Errorf("island #%d is not remote", island.Id)
+		}
+	}
+
+	evolver.Stop()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("worker: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("worker did not stop with the evolver")
+	}
+}
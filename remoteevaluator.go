@@ -0,0 +1,194 @@
+package goevolve
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/tsavo/GoVirtual"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Expensive evaluations can be farmed out to other machines: an
+// EvaluationServer scores programs posted to EvaluationPath as JSON, and a
+// RemoteEvaluator spreads a Population's evaluations across a pool of them.
+
+const EvaluationPath = "/evaluate"
+
+const DefaultEvaluationTimeout = time.Minute
+
+type EvaluationRequest struct {
+	Program string `json:"program"`
+}
+
+type EvaluationResponse struct {
+	Reward     float64   `json:"reward"`
+	Objectives []float64 `json:"objectives,omitempty"`
+	Behavior   []float64 `json:"behavior,omitempty"`
+}
+
+// FailureReward is what a RemoteEvaluator gives a program it could not score by
+// default: low enough to lose to any real score, yet small enough that the
+// statistics of a generation full of failures stay finite.
+const FailureReward = -math.MaxFloat32
+
+// FallibleEvaluator is implemented by evaluators that can fail to score a
+// program. On failure they still return the Fitness to give the program, but
+// the Population does not cache it so the program is evaluated again if it
+// comes back.
+type FallibleEvaluator interface {
+	Evaluator
+	TryEvaluateProgram(p *govirtual.Processor, program string) (Fitness, error)
+}
+
+// TryRunProgram is RunProgram for evaluators that may be fallible.
+func TryRunProgram(e Evaluator, p *govirtual.Processor, program string) (Fitness, error) {
+	if evaluator, ok := e.(FallibleEvaluator); ok {
+		return evaluator.TryEvaluateProgram(p, program)
+	}
+	return RunProgram(e, p, program), nil
+}
+
+// RemoteEvaluator posts each program to one of Workers, the base URLs of
+// EvaluationServers, taking them in turn. A request that fails or takes longer
+// than Timeout is retried on the next worker up to Retries times before the
+// program is given Failure. Set the Population's Workers to the number of
+// evaluations to keep in flight.
+type RemoteEvaluator struct {
+	Workers []string
+	Timeout time.Duration
+	Retries int
+	Failure Fitness
+	Client  *http.Client
+	next    uint32
+}
+
+func NewRemoteEvaluator(workers ...string) *RemoteEvaluator {
+	return &RemoteEvaluator{Workers: workers, Timeout: DefaultEvaluationTimeout, Retries: 2, Failure: Fitness{Reward: FailureReward}, Client: http.DefaultClient}
+}
+
+func (e *RemoteEvaluator) TryEvaluateProgram(p *govirtual.Processor, program string) (Fitness, error) {
+	if len(e.Workers) == 0 {
+		return e.Failure, errors.New("goevolve: remote evaluator has no workers")
+	}
+	var err error
+	for attempt := 0; attempt <= e.Retries; attempt++ {
+		worker := e.Workers[int((atomic.AddUint32(&e.next, 1)-1)%uint32(len(e.Workers)))]
+		var f Fitness
+		if f, err = e.post(worker, program); err == nil {
+			return f, nil
+		}
+	}
+	return e.Failure, err
+}
+
+func (e *RemoteEvaluator) EvaluateProgram(p *govirtual.Processor, program string) Fitness {
+	f, _ := e.TryEvaluateProgram(p, program)
+	return f
+}
+
+// Evaluate cannot see the program, so it returns Failure.
+func (e *RemoteEvaluator) Evaluate(p *govirtual.Processor) Fitness {
+	return e.Failure
+}
+
+func (e *RemoteEvaluator) post(worker, program string) (Fitness, error) {
+	body, err := json.Marshal(EvaluationRequest{program})
+	if err != nil {
+		return e.Failure, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), e.Timeout)
+	defer cancel()
+	req, err := http.NewRequest("POST", strings.TrimRight(worker, "/")+EvaluationPath, bytes.NewReader(body))
+	if err != nil {
+		return e.Failure, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := e.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return e.Failure, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return e.Failure, fmt.Errorf("goevolve: %s: %s: %s", worker, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	var out EvaluationResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return e.Failure, err
+	}
+	return Fitness{out.Reward, out.Objectives, out.Behavior}, nil
+}
+
+// EvaluationServer scores posted programs with Evaluator on its own pool of
+// Processors, so at most as many programs as it has Processors run at once.
+// With more than one the Evaluator must be safe for concurrent use.
+type EvaluationServer struct {
+	Evaluator  Evaluator
+	Heap       *govirtual.Memory
+	processors chan *govirtual.Processor
+}
+
+func NewEvaluationServer(workers int, heap *govirtual.Memory, rl int, is *govirtual.InstructionSet, term govirtual.TerminationCondition, eval Evaluator) *EvaluationServer {
+	server := &EvaluationServer{Evaluator: eval, Heap: heap, processors: make(chan *govirtual.Processor, Max(workers, 1))}
+	size := 0
+	if heap != nil {
+		size = len(*heap)
+	}
+	for x := 0; x < cap(server.processors); x++ {
+		private := make(govirtual.Memory, size)
+		server.processors <- govirtual.NewProcessor(x, rl, is, &private, &term)
+	}
+	return server
+}
+
+func (server *EvaluationServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "POST a program to evaluate", http.StatusMethodNotAllowed)
+		return
+	}
+	var req EvaluationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var pro *govirtual.Processor
+	select {
+	case pro = <-server.processors:
+	case <-r.Context().Done():
+		return
+	}
+	if server.Heap != nil {
+		copy(*pro.Heap, *server.Heap)
+	}
+	f, err := TryRunProgram(server.Evaluator, pro, req.Program)
+	server.processors <- pro
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	body, err := json.Marshal(EvaluationResponse{f.Reward, f.Objectives, f.Behavior})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
+
+// ListenAndServe serves the EvaluationServer at EvaluationPath on addr.
+func (server *EvaluationServer) ListenAndServe(addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle(EvaluationPath, server)
+	return http.ListenAndServe(addr, mux)
+}
@@ -0,0 +1,98 @@
+package goevolve
+
+import (
+	"sort"
+)
+
+// ReplacementStrategy decides which evaluated individuals form the next
+// generation. Offspring limits how many bred programs are evaluated each
+// generation; 0 evaluates all of them.
+type ReplacementStrategy interface {
+	Offspring(populationSize int) int
+	Replace(parents, offspring SolutionList) SolutionList
+}
+
+func sorted(solutions ...SolutionList) SolutionList {
+	out := make(SolutionList, 0)
+	for _, s := range solutions {
+		out = append(out, s...)
+	}
+	sort.Stable(out)
+	return out
+}
+
+// sample picks n of programs at random, so that whichever breeder comes first in
+// a MultiBreeder is not the only one whose children are evaluated.
+func sample(rng *SafeRNG, programs []string, n int) []string {
+	pool := append([]string{}, programs...)
+	for x := 0; x < n; x++ {
+		y := x + rng.Int()%(len(pool)-x)
+		pool[x], pool[y] = pool[y], pool[x]
+	}
+	return pool[:n]
+}
+
+// Generational replaces the whole population with the offspring, except that
+// the Elites best parents take the place of the worst offspring.
+type Generational struct {
+	Elites int
+}
+
+func (g Generational) Offspring(int) int { return 0 }
+
+func (g Generational) Replace(parents, offspring SolutionList) SolutionList {
+	if g.Elites <= 0 {
+		return offspring
+	}
+	elites := sorted(parents)
+	elites = elites[:Min(g.Elites, len(elites))]
+	return append(elites, sorted(offspring)[:Max(len(offspring)-len(elites), 0)]...)
+}
+
+// MuPlusLambda keeps the Mu best of parents and offspring together. Mu defaults
+// to the number of parents.
+type MuPlusLambda struct {
+	Mu int
+}
+
+func (m MuPlusLambda) Offspring(int) int { return 0 }
+
+func (m MuPlusLambda) Replace(parents, offspring SolutionList) SolutionList {
+	mu := m.Mu
+	if mu <= 0 {
+		mu = len(parents)
+	}
+	all := sorted(parents, offspring)
+	return all[:Min(mu, len(all))]
+}
+
+// MuCommaLambda keeps the Mu best offspring and discards every parent. Mu
+// defaults to the number of parents.
+type MuCommaLambda struct {
+	Mu int
+}
+
+func (m MuCommaLambda) Offspring(int) int { return 0 }
+
+func (m MuCommaLambda) Replace(parents, offspring SolutionList) SolutionList {
+	mu := m.Mu
+	if mu <= 0 {
+		mu = len(parents)
+	}
+	all := sorted(offspring)
+	return all[:Min(mu, len(all))]
+}
+
+// SteadyState evaluates only Count offspring per step and puts them in place of
+// the worst members of the population.
+type SteadyState struct {
+	Count int
+}
+
+func (st SteadyState) Offspring(int) int { return Max(st.Count, 1) }
+
+func (st SteadyState) Replace(parents, offspring SolutionList) SolutionList {
+	survivors := sorted(parents)
+	survivors = survivors[:Max(len(survivors)-len(offspring), 0)]
+	return append(survivors, offspring...)
+}
@@ -23,6 +23,18 @@ func (multi AndSelector) Select(s *SolutionList) *SolutionList {
 	return &solutions
 }
 
+func (multi AndSelector) InheritRNG(r *SafeRNG) {
+	for x, selector := range multi {
+		inheritRNG(selector, r.Derive(int64(x)))
+	}
+}
+
+func (multi AndSelector) UseArchive(archive *NoveltyArchive) {
+	for _, selector := range multi {
+		useArchive(selector, archive)
+	}
+}
+
 func (multi *AndSelector) AddSelector(s Selector) {
 	(*multi) = append(*multi, s)
 }
@@ -34,6 +46,18 @@ func OrSelect(selectors ...Selector) *OrSelector {
 	return &x
 }
 
+func (multi OrSelector) InheritRNG(r *SafeRNG) {
+	for x, selector := range multi {
+		inheritRNG(selector, r.Derive(int64(x)))
+	}
+}
+
+func (multi OrSelector) UseArchive(archive *NoveltyArchive) {
+	for _, selector := range multi {
+		useArchive(selector, archive)
+	}
+}
+
 func (multi OrSelector) Select(s *SolutionList) *SolutionList {
 	for _, x := range multi {
 		solution := (x).Select(s)
@@ -59,59 +83,31 @@ func (topx TopXSelector) Select(s *SolutionList) *SolutionList {
 	return &x
 }
 
-type StochasticUniversalSelector struct {
-	Keep int
-}
-
-func NewStochasticUniversalSelector(keep int) *StochasticUniversalSelector {
-	return &StochasticUniversalSelector{keep}
-}
-
-func (sel StochasticUniversalSelector) Select(s *SolutionList) *SolutionList {
-	sort.Sort(sort.Reverse(*s))
-	f := (*s)[len(*s)-1].Reward
-	n := sel.Keep
-	p := f / n
-	start := rng.Int()%p + 1
-	pointers := make([]int, n)
-	for i := 0; i < n; i++ {
-		pointers[i] = start + i*p
-	}
-	ret := RWS(s, pointers)
-	return ret
-}
-
-func RWS(solutions *SolutionList, pointers []int) *SolutionList {
-	keep := make(SolutionList, len(pointers))
-	i := 0
-	for _, p := range pointers {
-		for int((*solutions)[i].Reward) < p {
-			i++
-		}
-		keep = append(keep, (*solutions)[i])
-	}
-	return &keep
-}
-
 type TournamentSelector struct {
 	Keep int
+	RNGSource
 }
 
-func Tournament(keep int) TournamentSelector {
-	return TournamentSelector{keep}
+func Tournament(keep int) *TournamentSelector {
+	return &TournamentSelector{Keep: keep}
 }
 
 func (t TournamentSelector) Select(solutions *SolutionList) *SolutionList {
+	rng := t.random()
 	keepers := make(SolutionList, 0)
 	for x := 0; x < t.Keep; x++ {
-		keepers = append(keepers, FightInTournament((*solutions)[rng.Int()%len(*solutions)], (*solutions)[rng.Int()%len(*solutions)]))
+		keepers = append(keepers, fightInTournament(rng, (*solutions)[rng.Int()%len(*solutions)], (*solutions)[rng.Int()%len(*solutions)]))
 	}
 	return &keepers
 }
 
 func FightInTournament(warrior1 *Solution, warrior2 *Solution) *Solution {
+	return fightInTournament(rng, warrior1, warrior2)
+}
+
+func fightInTournament(rng *SafeRNG, warrior1 *Solution, warrior2 *Solution) *Solution {
 	var highest, lowest *Solution
-	if warrior1.Reward >= warrior2.Reward {
+	if warrior1.Reward > warrior2.Reward || (warrior1.Reward == warrior2.Reward && warrior1.Length <= warrior2.Length) {
 		highest, lowest = warrior1, warrior2
 	} else {
 		highest, lowest = warrior2, warrior1
@@ -119,7 +115,7 @@ func FightInTournament(warrior1 *Solution, warrior2 *Solution) *Solution {
 	if highest.Reward <= 0 {
 		return highest
 	}
-	if rng.Int()%highest.Reward > lowest.Reward/2 {
+	if rng.Float64()*highest.Reward > lowest.Reward/2 {
 		return highest
 	} else {
 		return lowest
@@ -0,0 +1,138 @@
+package goevolve
+
+import (
+	"github.com/tsavo/GoVirtual"
+	"strings"
+)
+
+// Simplifier strips dead code from programs: instructions that cannot be reached
+// because they follow an Unconditional instruction before the next referenced
+// label, labels nothing refers to except KeepLabels, and NoEffect instructions.
+// Jumps through registers are invisible to it, so SimplifySolution only keeps a
+// simplification that scores exactly the same when evaluated again.
+type Simplifier struct {
+	Unconditional, NoEffect, KeepLabels []string
+	*govirtual.InstructionSet
+}
+
+func NewSimplifier(is *govirtual.InstructionSet) *Simplifier {
+	return &Simplifier{Unconditional: []string{"jump", "return"}, NoEffect: []string{"noop"}, KeepLabels: []string{":start"}, InstructionSet: is}
+}
+
+// labelName drops the colon a label may be written with, as BasicBlocks does.
+func labelName(label string) string {
+	return strings.TrimPrefix(label, ":")
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Simplify removes dead code until nothing more can be removed. It does not check the result.
+func (simp *Simplifier) Simplify(program string) string {
+	genome := ParseGenome(program, simp.InstructionSet)
+	for {
+		simpler := simp.pass(program, genome)
+		if len(simpler) == len(genome) {
+			return simpler.String()
+		}
+		genome = simpler
+		program = genome.String()
+	}
+}
+
+func (simp *Simplifier) pass(program string, genome Genome) Genome {
+	names := genome.Labels()
+	if simp.InstructionSet != nil {
+		names = simp.CompileProgram(program, nil).LabelNames()
+	}
+	defined := make(map[string]bool)
+	for _, name := range names {
+		defined[labelName(name)] = true
+	}
+	referenced := make(map[string]bool)
+	for _, name := range simp.KeepLabels {
+		referenced[labelName(name)] = true
+	}
+	for _, gene := range genome {
+		for _, arg := range gene.Arguments {
+			if arg.IsLabel() && defined[labelName(arg.Label)] {
+				referenced[labelName(arg.Label)] = true
+			}
+		}
+	}
+	out := make(Genome, 0, len(genome))
+	reachable := true
+	for _, gene := range genome {
+		if gene.IsLabel() {
+			if referenced[labelName(gene.Label)] {
+				reachable = true
+				out = append(out, gene)
+			}
+			continue
+		}
+		if !reachable || contains(simp.NoEffect, gene.Name) {
+			continue
+		}
+		out = append(out, gene)
+		if contains(simp.Unconditional, gene.Name) {
+			reachable = false
+		}
+	}
+	return out
+}
+
+// SimplifySolution simplifies solution's program and returns the simplified
+// Solution if evaluate scores it identically, otherwise solution itself.
+func (simp *Simplifier) SimplifySolution(solution *Solution, evaluate func(program string) *Solution) *Solution {
+	program := simp.Simplify(solution.Program)
+	if program == solution.Program {
+		return solution
+	}
+	simplified := evaluate(program)
+	if !sameFitness(simplified.Fitness, solution.Fitness) {
+		return solution
+	}
+	return simplified
+}
+
+func sameFitness(a, b Fitness) bool {
+	if a.Reward != b.Reward || len(a.Objectives) != len(b.Objectives) {
+		return false
+	}
+	for x := range a.Objectives {
+		if a.Objectives[x] != b.Objectives[x] {
+			return false
+		}
+	}
+	return true
+}
+
+// simplify replaces the best solution of a generation with its verified
+// simplification. It returns how many programs it scored, hits of them from the Store.
+func (s *Population) simplify(solutions SolutionList) (evaluations, hits int) {
+	if s.Simplifier == nil || len(solutions) == 0 {
+		return
+	}
+	best := 0
+	for x := range solutions {
+		if solutions[x].Reward > solutions[best].Reward {
+			best = x
+		}
+	}
+	pro := s.processor(0)
+	solutions[best] = s.Simplifier.SimplifySolution(solutions[best], func(program string) *Solution {
+		solution, hit := s.evaluateProgram(pro, program)
+		evaluations++
+		if hit {
+			hits++
+		}
+		return solution
+	})
+	return
+}
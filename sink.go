@@ -0,0 +1,115 @@
+package goevolve
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ChampionSink receives every new champion found by an IslandEvolver.
+type ChampionSink interface {
+	Publish(champion Champion) error
+}
+
+var ErrSinkFull = errors.New("goevolve: champion sink is full")
+
+type MultiSink []ChampionSink
+
+func Sinks(sinks ...ChampionSink) *MultiSink {
+	out := MultiSink(sinks)
+	return &out
+}
+
+// Publish hands the champion to every sink and returns the first error.
+func (multi MultiSink) Publish(champion Champion) error {
+	var first error
+	for _, sink := range multi {
+		if err := sink.Publish(champion); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+type SinkFunc func(champion Champion) error
+
+func (f SinkFunc) Publish(champion Champion) error {
+	return f(champion)
+}
+
+// ChanSink delivers champions without blocking; Publish fails with ErrSinkFull when nobody is reading.
+type ChanSink chan Champion
+
+func (sink ChanSink) Publish(champion Champion) error {
+	select {
+	case sink <- champion:
+		return nil
+	default:
+		return ErrSinkFull
+	}
+}
+
+// FileSink atomically replaces the file at Path with the champion's best program.
+type FileSink struct {
+	Path string
+}
+
+func NewFileSink(path string) *FileSink {
+	return &FileSink{path}
+}
+
+func (sink *FileSink) Publish(champion Champion) error {
+	if len(champion.Programs) == 0 {
+		return nil
+	}
+	return writeFileAtomic(sink.Path, []byte(champion.Programs[0]))
+}
+
+// GitSink writes the champion's best program to Path inside the repository at Dir
+// and commits it. It only pushes when Push is set.
+type GitSink struct {
+	Dir, Path, Message string
+	Push               bool
+	Remote             string
+}
+
+func NewGitSink(dir, path string) *GitSink {
+	return &GitSink{Dir: dir, Path: path, Message: "Automated commit of best program so far"}
+}
+
+func (sink *GitSink) Publish(champion Champion) error {
+	if err := (&FileSink{filepath.Join(sink.Dir, sink.Path)}).Publish(champion); err != nil {
+		return err
+	}
+	if _, err := sink.git("add", "--", sink.Path); err != nil {
+		return err
+	}
+	changed, err := sink.git("status", "--porcelain", "--", sink.Path)
+	if err != nil || len(strings.TrimSpace(changed)) == 0 {
+		return err
+	}
+	if _, err := sink.git("commit", "-m", sink.Message, "--", sink.Path); err != nil {
+		return err
+	}
+	if !sink.Push {
+		return nil
+	}
+	if sink.Remote != "" {
+		_, err = sink.git("push", sink.Remote)
+	} else {
+		_, err = sink.git("push")
+	}
+	return err
+}
+
+func (sink *GitSink) git(args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = sink.Dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return string(out), fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(string(out)))
+	}
+	return string(out), nil
+}
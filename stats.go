@@ -0,0 +1,63 @@
+package goevolve
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+type GenerationStats struct {
+	Generation                          int
+	Duration                            time.Duration
+	Evaluations, CacheHits, CacheMisses int
+	Best, Mean, Median, Worst, StdDev   float64
+	MeanLength                          float64
+	UniquePrograms                      int
+}
+
+// NewGenerationStats summarises the population in solutions after evaluations
+// programs were scored, hits of them from the SolutionStore.
+func NewGenerationStats(generation int, duration time.Duration, solutions SolutionList, evaluations, hits int) GenerationStats {
+	stats := GenerationStats{Generation: generation, Duration: duration, Evaluations: evaluations, CacheHits: hits, CacheMisses: evaluations - hits}
+	if len(solutions) == 0 {
+		return stats
+	}
+	rewards := make([]float64, len(solutions))
+	unique := make(map[string]bool)
+	sum, length := 0.0, 0
+	for x, solution := range solutions {
+		rewards[x] = solution.Reward
+		sum += rewards[x]
+		length += ProgramLength(solution.Program)
+		unique[solution.Program] = true
+	}
+	sort.Float64s(rewards)
+	n := float64(len(rewards))
+	stats.Best, stats.Worst = rewards[len(rewards)-1], rewards[0]
+	stats.Mean = sum / n
+	if len(rewards)%2 == 0 {
+		stats.Median = (rewards[len(rewards)/2-1] + rewards[len(rewards)/2]) / 2
+	} else {
+		stats.Median = rewards[len(rewards)/2]
+	}
+	variance := 0.0
+	for _, reward := range rewards {
+		variance += (reward - stats.Mean) * (reward - stats.Mean)
+	}
+	stats.StdDev = math.Sqrt(variance / n)
+	stats.MeanLength = float64(length) / n
+	stats.UniquePrograms = len(unique)
+	return stats
+}
+
+// ProgramLength counts the non-blank lines of a program.
+func ProgramLength(program string) int {
+	length := 0
+	for _, line := range strings.Split(program, "\n") {
+		if len(strings.TrimSpace(line)) > 0 {
+			length++
+		}
+	}
+	return length
+}
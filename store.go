@@ -0,0 +1,226 @@
+package goevolve
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SolutionStore caches evaluated Solutions by SolutionKey. Implementations must be safe for concurrent use.
+type SolutionStore interface {
+	Get(key string) (*Solution, bool)
+	Put(key string, solution *Solution)
+	Iterate(func(key string, solution *Solution) bool)
+	Flush() error
+	Close() error
+}
+
+const (
+	DefaultStoreShards = 32
+	StoreVersion       = 2
+)
+
+// storeFile is the on-disk layout of a FileStore.
+type storeFile struct {
+	Version   int
+	Solutions map[string]*Solution
+}
+
+func SolutionKey(program string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(program)))
+}
+
+type storeShard struct {
+	sync.RWMutex
+	solutions map[string]*Solution
+}
+
+type MemoryStore struct {
+	shards []*storeShard
+}
+
+func NewMemoryStore(shards int) *MemoryStore {
+	if shards < 1 {
+		shards = 1
+	}
+	store := &MemoryStore{make([]*storeShard, shards)}
+	for x := range store.shards {
+		store.shards[x] = &storeShard{solutions: make(map[string]*Solution)}
+	}
+	return store
+}
+
+func (store *MemoryStore) shard(key string) *storeShard {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return store.shards[h.Sum32()%uint32(len(store.shards))]
+}
+
+func (store *MemoryStore) Get(key string) (*Solution, bool) {
+	shard := store.shard(key)
+	shard.RLock()
+	defer shard.RUnlock()
+	solution, ok := shard.solutions[key]
+	return solution, ok
+}
+
+func (store *MemoryStore) Put(key string, solution *Solution) {
+	shard := store.shard(key)
+	shard.Lock()
+	defer shard.Unlock()
+	shard.solutions[key] = solution
+}
+
+// Iterate calls f for every stored Solution until f returns false. f must not call back into the store.
+func (store *MemoryStore) Iterate(f func(key string, solution *Solution) bool) {
+	for _, shard := range store.shards {
+		shard.RLock()
+		for key, solution := range shard.solutions {
+			if !f(key, solution) {
+				shard.RUnlock()
+				return
+			}
+		}
+		shard.RUnlock()
+	}
+}
+
+func (store *MemoryStore) Flush() error { return nil }
+func (store *MemoryStore) Close() error { return nil }
+
+// NamespacedStore shares an underlying store with others while keeping its
+// Solutions apart: every key is prefixed with Namespace. Close leaves the
+// underlying store open.
+type NamespacedStore struct {
+	Store     SolutionStore
+	Namespace string
+}
+
+func Namespaced(store SolutionStore, namespace string) *NamespacedStore {
+	return &NamespacedStore{store, namespace}
+}
+
+func (store *NamespacedStore) Get(key string) (*Solution, bool) {
+	return store.Store.Get(store.Namespace + key)
+}
+
+func (store *NamespacedStore) Put(key string, solution *Solution) {
+	store.Store.Put(store.Namespace+key, solution)
+}
+
+func (store *NamespacedStore) Iterate(f func(key string, solution *Solution) bool) {
+	store.Store.Iterate(func(key string, solution *Solution) bool {
+		if !strings.HasPrefix(key, store.Namespace) {
+			return true
+		}
+		return f(strings.TrimPrefix(key, store.Namespace), solution)
+	})
+}
+
+func (store *NamespacedStore) Flush() error { return store.Store.Flush() }
+func (store *NamespacedStore) Close() error { return nil }
+
+// FileStore is a MemoryStore persisted as a gob file. It is loaded when opened and
+// written atomically on Flush, on Close and optionally every flush interval. A
+// file that cannot be decoded, such as one written by an older version, is
+// logged and replaced by an empty cache.
+type FileStore struct {
+	*MemoryStore
+	Path   string
+	stop   chan bool
+	closed sync.Once
+	wait   sync.WaitGroup
+	flush  sync.Mutex
+}
+
+func OpenFileStore(path string, flushInterval time.Duration) (*FileStore, error) {
+	store := &FileStore{MemoryStore: NewMemoryStore(DefaultStoreShards), Path: path, stop: make(chan bool)}
+	data, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if len(data) > 0 {
+		var file storeFile
+		if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&file); err != nil {
+			log.Printf("Ignoring %s: %v", path, err)
+			file.Solutions = nil
+		} else if file.Version != StoreVersion {
+			log.Printf("Ignoring %s: unsupported store version %d", path, file.Version)
+			file.Solutions = nil
+		}
+		for key, solution := range file.Solutions {
+			store.Put(key, solution)
+		}
+	}
+	if flushInterval > 0 {
+		store.wait.Add(1)
+		go func() {
+			defer store.wait.Done()
+			for {
+				select {
+				case <-store.stop:
+					return
+				case <-time.After(flushInterval):
+					if err := store.Flush(); err != nil {
+						log.Printf("Flushing %s: %v", path, err)
+					}
+				}
+			}
+		}()
+	}
+	return store, nil
+}
+
+func (store *FileStore) Flush() error {
+	store.flush.Lock()
+	defer store.flush.Unlock()
+	file := storeFile{StoreVersion, make(map[string]*Solution)}
+	store.Iterate(func(key string, solution *Solution) bool {
+		file.Solutions[key] = solution
+		return true
+	})
+	b := new(bytes.Buffer)
+	if err := gob.NewEncoder(b).Encode(&file); err != nil {
+		return err
+	}
+	return writeFileAtomic(store.Path, b.Bytes())
+}
+
+// Close stops the periodic flush and writes the store. It may be called more than once.
+func (store *FileStore) Close() error {
+	store.closed.Do(func() {
+		close(store.stop)
+	})
+	store.wait.Wait()
+	return store.Flush()
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return nil
+}